@@ -11,6 +11,7 @@ import (
 	"github.com/voodooEntity/cyberbrain/src/example"
 	"github.com/voodooEntity/cyberbrain/src/system/archivist"
 	"github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+	"github.com/voodooEntity/cyberbrain/src/system/util"
 	"log"
 )
 
@@ -65,8 +66,8 @@ func main() {
 	obsi.Loop()
 
 	// history is enabled so we can lookup the
-	// executed jobs
-	qry := gits.NewQuery().Read("Job")
-	res := gits.GetDefault().Query().Execute(qry)
+	// executed jobs of this cyberbrain instance
+	qry := gits.NewQuery().Read("Job").Match("Context", "==", util.Scope("System", "GreatName"))
+	res := cb.GetGitsInstance().Query().Execute(qry)
 	fmt.Println(fmt.Sprintf("%+v", res))
 }
@@ -1,10 +1,16 @@
 package cyberbrain
 
 import (
+	"context"
 	"errors"
 	"log"
 	"os"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/voodooEntity/gits"
 	"github.com/voodooEntity/gits/src/transport"
@@ -18,10 +24,19 @@ import (
 type Cyberbrain struct {
 	ident        string
 	neuronAmount int
-	neurons      []int
+	neurons      []*cerebrum.Neuron
+	retiring     []*cerebrum.Neuron
+	neuronMutex  sync.Mutex
+	nextNeuronID int
 	con          cerebrum.Consciousness
 	log          *archivist.Archivist
 	initCfg      Settings
+	ctx          context.Context
+	cancel       context.CancelFunc
+	jobCtx       context.Context
+	abortJobs    context.CancelCauseFunc
+	running      *sync.WaitGroup
+	started      bool
 }
 
 type Settings struct {
@@ -32,6 +47,60 @@ type Settings struct {
 	LogLevel     int
 	DebugLevel   int
 	History      bool
+	Recovery     RecoveryPolicy
+	// PriorityAging is the time after which a waiting job gains one
+	// priority point. Defaults to 10s, a negative value disables aging
+	PriorityAging time.Duration
+	// CategoryConcurrency limits the amount of jobs executed at the
+	// same time per action category. Categories not listed are unlimited
+	CategoryConcurrency map[string]int
+	// WitnessSweepInterval is how often expired witnesses are garbage
+	// collected. Defaults to 1m, a negative value disables the sweep
+	WitnessSweepInterval time.Duration
+	// BackfillOnRegister schedules every registered action against
+	// the data already stored in the gits instance
+	BackfillOnRegister bool
+	// BackfillBatchSize is the amount of root entities paged and
+	// loaded per batch of a backfill or a periodic run. Defaults to 100
+	BackfillBatchSize int
+}
+
+// RecoveryPolicy defines how jobs orphaned by an unclean shutdown
+// are handled when the cyberbrain is started again
+type RecoveryPolicy string
+
+const (
+	// RECOVERY_REOPEN puts orphaned jobs back to Open (default)
+	RECOVERY_REOPEN RecoveryPolicy = "Reopen"
+	// RECOVERY_FAIL deletes orphaned jobs
+	RECOVERY_FAIL RecoveryPolicy = "Fail"
+	// RECOVERY_DEAD_LETTER moves orphaned jobs to the Failed state
+	RECOVERY_DEAD_LETTER RecoveryPolicy = "DeadLetter"
+)
+
+// DEFAULT_PRIORITY_AGING is used if Settings.PriorityAging is not set
+const DEFAULT_PRIORITY_AGING = 10 * time.Second
+
+// DEFAULT_WITNESS_SWEEP_INTERVAL is used if Settings.WitnessSweepInterval is not set
+const DEFAULT_WITNESS_SWEEP_INTERVAL = time.Minute
+
+// DEFAULT_BACKFILL_BATCH_SIZE is used if Settings.BackfillBatchSize is not set
+const DEFAULT_BACKFILL_BATCH_SIZE = 100
+
+// AbortedJobsError is returned by Shutdown if the given context expired
+// before all neurons finished their current job. Jobs contains the IDs
+// of the jobs that were still being executed at that point, they have
+// been aborted and reopened.
+type AbortedJobsError struct {
+	Jobs []int
+}
+
+func (e *AbortedJobsError) Error() string {
+	ids := make([]string, 0, len(e.Jobs))
+	for _, id := range e.Jobs {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	return "cyberbrain shutdown aborted jobs: [" + strings.Join(ids, ",") + "]"
 }
 
 func New(cfg Settings) *Cyberbrain {
@@ -58,11 +127,40 @@ func New(cfg Settings) *Cyberbrain {
 		ident:        cfg.Ident,
 		con:          cerebrum.Consciousness{},
 		neuronAmount: runtime.NumCPU(), // default neuron amount is num logical cpus
-		neurons:      make([]int, 0),
+		neurons:      make([]*cerebrum.Neuron, 0),
+		retiring:     make([]*cerebrum.Neuron, 0),
 		log:          arc,
 		initCfg:      cfg,
 	}
 
+	// orphaned jobs are reopened by default
+	if "" == instance.initCfg.Recovery {
+		instance.initCfg.Recovery = RECOVERY_REOPEN
+	}
+
+	// copy the category limits, they are shared by all neurons
+	if nil != cfg.CategoryConcurrency {
+		instance.initCfg.CategoryConcurrency = make(map[string]int, len(cfg.CategoryConcurrency))
+		for category, limit := range cfg.CategoryConcurrency {
+			instance.initCfg.CategoryConcurrency[category] = limit
+		}
+	}
+
+	// waiting jobs gain priority over time by default
+	if 0 == instance.initCfg.PriorityAging {
+		instance.initCfg.PriorityAging = DEFAULT_PRIORITY_AGING
+	}
+
+	// expired witnesses are swept by default
+	if 0 == instance.initCfg.WitnessSweepInterval {
+		instance.initCfg.WitnessSweepInterval = DEFAULT_WITNESS_SWEEP_INTERVAL
+	}
+
+	// backfills and periodic runs are loaded in batches
+	if 0 >= instance.initCfg.BackfillBatchSize {
+		instance.initCfg.BackfillBatchSize = DEFAULT_BACKFILL_BATCH_SIZE
+	}
+
 	// if the given neuronAmount is
 	// a positive >0 int
 	if cfg.NeuronAmount > 0 {
@@ -95,41 +193,199 @@ func (cb *Cyberbrain) GetGitsInstance() *gits.Gits {
 }
 
 func (cb *Cyberbrain) Start() error {
+	return cb.StartContext(context.Background())
+}
+
+// StartContext starts the cyberbrain bound to the given context. Once the
+// context is done the neurons stop picking up new jobs and the jobs they
+// are currently working on are aborted and reopened. Use Shutdown to let
+// running jobs finish.
+func (cb *Cyberbrain) StartContext(ctx context.Context) error {
 	// make sure we dont start the same
-	// cyberbrain instance twice
-	if util.IsAlive(cb.con.Memory.Gits) {
-		return errors.New("cyberbrain already running")
+	// cyberbrain instance twice. If we find an alive marker
+	// without having started ourself it has been left
+	// behind by a run that did not shut down cleanly
+	if util.IsAlive(cb.con.Memory.Gits, cb.ident) {
+		if cb.isStarted() {
+			return errors.New("cyberbrain already running")
+		}
+		cb.log.Warning("Found stale alive marker, previous run did not shut down cleanly")
 	}
 
+	// resume where a previous run stopped
+	cb.recover()
+
+	// running jobs are bound to the callers context, the neurons to
+	// our own one so shutdown can signal them without aborting jobs
+	cb.jobCtx, cb.abortJobs = context.WithCancelCause(ctx)
+	cb.ctx, cb.cancel = context.WithCancel(cb.jobCtx)
+	// every run waits for its own neurons, the ones a timed out
+	// Shutdown left behind may still be finishing their job
+	cb.running = &sync.WaitGroup{}
+
 	// set the "alife" dataset
 	cb.bringToLife()
 
 	// bootstrap our neurons
 	cb.startNeurons()
 
+	// and our sense of time
+	running := cb.running
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		cb.con.Activity.Timer.LoopContext(cb.ctx)
+	}()
+	cb.setStarted(true)
+
 	return nil
 }
 
+// Stop stops the cyberbrain right away. Running jobs are aborted and
+// reopened, Stop returns once all neurons exited.
 func (cb *Cyberbrain) Stop() error {
-	if !util.IsAlive(cb.con.Memory.Gits) {
+	if !util.IsAlive(cb.con.Memory.Gits, cb.ident) {
 		return errors.New("can't stop not running cyberebrain")
 	}
 
-	util.Terminate(cb.con.Memory.Gits)
+	if nil != cb.cancel {
+		cb.cancel()
+		cb.abortJobs(cerebrum.ErrJobAborted)
+	}
+	// make sure no neuron still works on a job, else
+	// a restart would recover and run it a second time
+	cb.running.Wait()
+
+	util.Terminate(cb.con.Memory.Gits, cb.ident)
+	cb.setStarted(false)
+
+	return nil
+}
+
+// Shutdown gracefully stops the cyberbrain. Neurons stop picking up new
+// jobs and Shutdown waits for the jobs currently in flight to finish. If
+// ctx is done before that, the running jobs are aborted and an
+// AbortedJobsError listing them is returned right away. Their neurons
+// exit in the background, reopening the jobs once the actions returned.
+// Actions only implementing Execute can't be interrupted and keep running
+// until they return, restarting the cyberbrain meanwhile may run their
+// jobs a second time.
+func (cb *Cyberbrain) Shutdown(ctx context.Context) error {
+	if !util.IsAlive(cb.con.Memory.Gits, cb.ident) {
+		return errors.New("can't stop not running cyberebrain")
+	}
+
+	// stop the neurons from picking new jobs
+	cb.cancel()
+
+	// wait for all neurons to drain in the background
+	// so we can also react on the given context
+	drained := make(chan struct{})
+	running := cb.running
+	go func() {
+		running.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+		cb.log.Info("All neurons drained, shutting down")
+	case <-ctx.Done():
+		aborted := cerebrum.GetRunningJobIDs(cb.con.Memory)
+		cb.log.Warning("Shutdown timed out, aborting running jobs", aborted)
+		err = &AbortedJobsError{Jobs: aborted}
+	}
+	cb.abortJobs(cerebrum.ErrJobAborted)
+
+	util.Terminate(cb.con.Memory.Gits, cb.ident)
+	cb.setStarted(false)
+
+	return err
+}
+
+// isStarted reports whether the cyberbrain has been started and not
+// stopped since
+func (cb *Cyberbrain) isStarted() bool {
+	cb.neuronMutex.Lock()
+	defer cb.neuronMutex.Unlock()
+	return cb.started
+}
+
+// setStarted marks the cyberbrain as started or stopped
+func (cb *Cyberbrain) setStarted(started bool) {
+	cb.neuronMutex.Lock()
+	defer cb.neuronMutex.Unlock()
+	cb.started = started
+}
+
+// ScaleNeurons adds or retires neurons of a running cyberbrain until
+// amount neurons are active. Retired neurons finish their current job
+// before they are marked Dead, until then their job can still be
+// cancelled and Shutdown waits for it.
+func (cb *Cyberbrain) ScaleNeurons(amount int) error {
+	if !util.IsAlive(cb.con.Memory.Gits, cb.ident) {
+		return errors.New("cyberbrain not running")
+	}
+	if 0 > amount {
+		return errors.New("neuron amount can't be negative")
+	}
+
+	cb.neuronMutex.Lock()
+	defer cb.neuronMutex.Unlock()
+
+	cb.log.Info("Scaling neurons", len(cb.neurons), amount)
+	// spawn new neurons if we have to few
+	for len(cb.neurons) < amount {
+		cb.spawnNeuron()
+	}
+
+	// and retire the latest ones if we have to many
+	for len(cb.neurons) > amount {
+		last := cb.neurons[len(cb.neurons)-1]
+		last.Retire()
+		cb.neurons = cb.neurons[:len(cb.neurons)-1]
+		cb.retiring = append(cb.retiring, last)
+	}
+	cb.neuronAmount = amount
 
 	return nil
 }
 
 func (cb *Cyberbrain) RegisterAction(actionName string, actionFactory func() interfaces.ActionInterface) error {
-	if util.IsAlive(cb.con.Memory.Gits) {
+	if cb.isStarted() {
 		return errors.New("cyberbrain already running, can't register new actions")
 	}
-	cb.con.Cortex.RegisterAction(actionName, actionFactory)
+	if err := cb.con.Cortex.RegisterAction(actionName, actionFactory); nil != err {
+		return err
+	}
+	if cb.initCfg.BackfillOnRegister {
+		if _, err := cb.Backfill(actionName, ""); nil != err {
+			return err
+		}
+	}
 	return nil
 }
 
+// Backfill schedules a registered action against matching data that has been
+// stored before the action was known. depName limits the backfill to a single
+// dependency, an empty depName backfills all of them. Inputs the action already
+// ran on are skipped. Returns the amount of jobs created
+func (cb *Cyberbrain) Backfill(actionName string, depName string) (int, error) {
+	act, err := cb.con.Cortex.GetAction(actionName)
+	if nil != err {
+		return 0, err
+	}
+	created, err := cb.con.Activity.Scheduler.Backfill(act, depName, cb.initCfg.BackfillBatchSize)
+	if nil != err {
+		return created, err
+	}
+	cb.log.Info("Backfilled action", actionName, created)
+	return created, nil
+}
+
 func (cb *Cyberbrain) LearnAndSchedule(data transport.TransportEntity) (transport.TransportEntity, error) {
-	if !util.IsAlive(cb.con.Memory.Gits) {
+	if !util.IsAlive(cb.con.Memory.Gits, cb.ident) {
 		return transport.TransportEntity{}, errors.New("cyberbrain not running")
 	}
 
@@ -145,8 +401,26 @@ func (cb *Cyberbrain) LearnAndSchedule(data transport.TransportEntity) (transpor
 	return learnedData, nil
 }
 
+// LearnAndScheduleWithPriority works like LearnAndSchedule but all jobs
+// created from the data, and their follow up jobs, get the given priority
+// instead of the priority configured on their action
+func (cb *Cyberbrain) LearnAndScheduleWithPriority(data transport.TransportEntity, priority int) (transport.TransportEntity, error) {
+	if !util.IsAlive(cb.con.Memory.Gits, cb.ident) {
+		return transport.TransportEntity{}, errors.New("cyberbrain not running")
+	}
+
+	learnedData, err := cb.Learn(data)
+	if err != nil {
+		return transport.TransportEntity{}, err
+	}
+
+	cb.con.Activity.Scheduler.RunWithPriority(learnedData, cb.con.Cortex, strconv.Itoa(priority))
+
+	return learnedData, nil
+}
+
 func (cb *Cyberbrain) Learn(data transport.TransportEntity) (transport.TransportEntity, error) {
-	if !util.IsAlive(cb.con.Memory.Gits) {
+	if !util.IsAlive(cb.con.Memory.Gits, cb.ident) {
 		return transport.TransportEntity{}, errors.New("cyberbrain not running")
 	}
 
@@ -155,7 +429,7 @@ func (cb *Cyberbrain) Learn(data transport.TransportEntity) (transport.Transport
 }
 
 func (cb *Cyberbrain) Schedule(data transport.TransportEntity) error {
-	if !util.IsAlive(cb.con.Memory.Gits) {
+	if !util.IsAlive(cb.con.Memory.Gits, cb.ident) {
 		return errors.New("cyberbrain not running")
 	}
 
@@ -163,13 +437,117 @@ func (cb *Cyberbrain) Schedule(data transport.TransportEntity) error {
 	return nil
 }
 
+// ScheduleWithTrace schedules like Schedule and returns the decisions the
+// scheduler took for the data, e.g. to see why an action did or didn't run
+func (cb *Cyberbrain) ScheduleWithTrace(data transport.TransportEntity) (*cerebrum.Trace, error) {
+	if !util.IsAlive(cb.con.Memory.Gits, cb.ident) {
+		return nil, errors.New("cyberbrain not running")
+	}
+
+	return cb.con.Activity.Scheduler.RunWithTrace(data, cb.con.Cortex), nil
+}
+
+// Explain returns the decisions the scheduler would take for the dependency
+// depName of an action if the stored entity entityType:entityID had just been
+// learned. Nothing is scheduled, so it can be used on a stopped cyberbrain
+func (cb *Cyberbrain) Explain(actionName string, depName string, entityType string, entityID int) (*cerebrum.Trace, error) {
+	return cb.con.Activity.Scheduler.Explain(cb.con.Cortex, actionName, depName, entityType, entityID)
+}
+
+// CancelJob cancels the context of a running job. The job is moved
+// to the dead letter queue marked as cancelled and won't be retried
+func (cb *Cyberbrain) CancelJob(id int) error {
+	cb.neuronMutex.Lock()
+	defer cb.neuronMutex.Unlock()
+	for _, neuron := range append(cb.neurons, cb.retiring...) {
+		if neuron.CancelJob(id) {
+			cb.log.Info("Cancelled job", id)
+			return nil
+		}
+	}
+	return errors.New("job " + strconv.Itoa(id) + " is not running")
+}
+
+// ListFailedJobs returns all jobs in the dead letter queue
+func (cb *Cyberbrain) ListFailedJobs() []cerebrum.FailedJob {
+	failed := make([]cerebrum.FailedJob, 0)
+	for _, id := range cerebrum.GetJobIDsByState(cb.con.Memory, "Failed") {
+		job := cerebrum.Load(id, cb.con.Memory, cb.log)
+		if nil != job {
+			failed = append(failed, job.GetFailedJob())
+		}
+	}
+	return failed
+}
+
+// InspectFailedJob returns a single job of the dead letter queue
+// including its input
+func (cb *Cyberbrain) InspectFailedJob(id int) (cerebrum.FailedJob, error) {
+	job, err := cb.loadFailedJob(id)
+	if nil != err {
+		return cerebrum.FailedJob{}, err
+	}
+	return job.GetFailedJob(), nil
+}
+
+// RequeueFailedJob puts a job of the dead letter queue back to Open. The
+// witness of a dead lettered job is never consulted, it is run again in
+// any case. If another Open or Assigned job has been scheduled for the
+// same input the requeue is refused unless allowDuplicate is given, in
+// which case both jobs will run
+func (cb *Cyberbrain) RequeueFailedJob(id int, allowDuplicate bool) error {
+	job, err := cb.loadFailedJob(id)
+	if nil != err {
+		return err
+	}
+	failed := job.GetFailedJob()
+	if !allowDuplicate && cerebrum.HasActiveJobWithWitness(cb.con.Memory, failed.Witness, id) {
+		return errors.New("an active job with the same witness exists for job " + strconv.Itoa(id))
+	}
+	job.Requeue()
+	cb.log.Info("Requeued failed job", id)
+	return nil
+}
+
+// PurgeFailedJobs deletes the given jobs of the dead letter queue
+// with their input. Without ids the whole queue is purged. All ids are
+// checked first, if one of them isn't in the queue nothing is purged
+func (cb *Cyberbrain) PurgeFailedJobs(ids ...int) (int, error) {
+	if 0 == len(ids) {
+		ids = cerebrum.GetJobIDsByState(cb.con.Memory, "Failed")
+	}
+	jobs := make([]*cerebrum.Job, 0, len(ids))
+	for _, id := range ids {
+		job, err := cb.loadFailedJob(id)
+		if nil != err {
+			return 0, err
+		}
+		jobs = append(jobs, job)
+	}
+	for _, job := range jobs {
+		job.Delete()
+	}
+	return len(jobs), nil
+}
+
 func (cb *Cyberbrain) GetObserverInstance(callback func(memoryInstance *cerebrum.Memory), lethal bool) *observer.Observer {
-	return observer.New(cb.con.Memory, cb.neuronAmount, callback, cb.log, lethal)
+	cb.neuronMutex.Lock()
+	neuronAmount := cb.neuronAmount
+	cb.neuronMutex.Unlock()
+	return observer.New(cb.con.Memory, neuronAmount, callback, cb.log, lethal)
 }
 
 //   - - - - - - - - - - - - - - - - - - - - - - - - - - - -
 //     INTERNAL FUNCTIONS
 //   - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+func (cb *Cyberbrain) loadFailedJob(id int) (*cerebrum.Job, error) {
+	job := cerebrum.Load(id, cb.con.Memory, cb.log)
+	if nil == job || "Failed" != job.GetState() {
+		return nil, errors.New("job " + strconv.Itoa(id) + " is not in the dead letter queue")
+	}
+	return job, nil
+}
+
 func (cb *Cyberbrain) setupActivity() {
 	activities := cerebrum.Activity{}
 
@@ -179,6 +557,16 @@ func (cb *Cyberbrain) setupActivity() {
 	// than scheduler
 	activities.Scheduler = cerebrum.NewScheduler(cb.con.Memory, activities.Demultiplexer, cb.log)
 
+	// and the rate limiter shared by all neurons
+	activities.RateLimiter = cerebrum.NewRateLimiter()
+
+	// the timer creates the jobs of periodic actions
+	activities.Timer = cerebrum.NewTimer(cb.con.Memory, cb.con.Cortex, activities.Scheduler, cb.log)
+	if 0 < cb.initCfg.WitnessSweepInterval {
+		activities.Timer.SetSweepInterval(cb.initCfg.WitnessSweepInterval)
+	}
+	activities.Timer.SetBatchSize(cb.initCfg.BackfillBatchSize)
+
 	// finally store it
 	cb.con.Activity = &activities
 }
@@ -199,29 +587,119 @@ func (cb *Cyberbrain) setupMemory(customGits *gits.Gits) {
 	cb.con.Memory = &cerebrum.Memory{
 		Mapper: mapperInstance,
 		Gits:   gitsInstance,
+		Ident:  cb.ident,
 	}
 }
 
 func (cb *Cyberbrain) startNeurons() {
+	cb.neuronMutex.Lock()
+	defer cb.neuronMutex.Unlock()
+
+	// neurons of a previous run are dead by now
+	cb.neurons = make([]*cerebrum.Neuron, 0)
+	cb.retiring = make([]*cerebrum.Neuron, 0)
 	for i := 0; i < cb.neuronAmount; i++ {
-		instance := cerebrum.NewNeuron(i, cb.con.Cortex, cb.con.Memory, cb.con.Activity, cb.log)
-		if cb.initCfg.History {
-			instance.EnableHistory()
+		cb.spawnNeuron()
+	}
+}
+
+// spawnNeuron creates and starts a new neuron. neuron ids are never
+// reused since they identify the Neuron entity in memory. Caller has
+// to hold the neuronMutex
+func (cb *Cyberbrain) spawnNeuron() {
+	instance := cerebrum.NewNeuron(cb.nextNeuronID, cb.con.Cortex, cb.con.Memory, cb.con.Activity, cb.log)
+	cb.nextNeuronID++
+	if cb.initCfg.History {
+		instance.EnableHistory()
+	}
+	if 0 < cb.initCfg.PriorityAging {
+		instance.SetPriorityAging(cb.initCfg.PriorityAging)
+	}
+	if nil != cb.initCfg.CategoryConcurrency {
+		instance.SetCategoryLimits(cb.initCfg.CategoryConcurrency)
+	}
+	instance.SetJobContext(cb.jobCtx)
+	running := cb.running
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		instance.LoopContext(cb.ctx)
+		cb.forgetNeuron(instance)
+	}()
+	cb.neurons = append(cb.neurons, instance)
+}
+
+// forgetNeuron drops a retired neuron once its loop exited and it
+// has been marked Dead
+func (cb *Cyberbrain) forgetNeuron(instance *cerebrum.Neuron) {
+	cb.neuronMutex.Lock()
+	defer cb.neuronMutex.Unlock()
+	for i, neuron := range cb.retiring {
+		if neuron == instance {
+			cb.retiring = append(cb.retiring[:i], cb.retiring[i+1:]...)
+			return
+		}
+	}
+}
+
+// recover handles everything a previous run left behind. Jobs still linked
+// to neurons or Assigned with their input present never finished and are
+// handled according to the configured RecoveryPolicy. Neurons of previous
+// runs are removed since they are not alive anymore
+func (cb *Cyberbrain) recover() {
+	orphaned := make(map[int]bool)
+	for _, id := range cerebrum.GetRunningJobIDs(cb.con.Memory) {
+		orphaned[id] = true
+	}
+	for _, id := range cerebrum.GetOrphanedJobIDs(cb.con.Memory) {
+		orphaned[id] = true
+	}
+
+	if removed := cerebrum.RemoveNeurons(cb.con.Memory); 0 < removed {
+		cb.log.Info("Removed neurons of previous run", removed)
+	}
+
+	if 0 == len(orphaned) {
+		return
+	}
+
+	ids := make([]int, 0, len(orphaned))
+	for id := range orphaned {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	cb.log.Warning("Recovering orphaned jobs with policy "+string(cb.initCfg.Recovery), ids)
+
+	for _, id := range ids {
+		job := cerebrum.Load(id, cb.con.Memory, cb.log)
+		if nil == job {
+			continue
+		}
+		switch cb.initCfg.Recovery {
+		case RECOVERY_FAIL:
+			job.Delete()
+		case RECOVERY_DEAD_LETTER:
+			job.DeadLetter("job orphaned by unclean shutdown", job.GetAttempts()+1)
+		default:
+			job.SetState("Open")
 		}
-		go instance.Loop()
-		cb.neurons = append(cb.neurons, i)
 	}
 }
 
 func (cb *Cyberbrain) bringToLife() {
+	// upsert the AI entity within our own scope, a previous
+	// run of this cyberbrain may have left it in state Dead
 	properties := make(map[string]string)
 	properties["State"] = "Alive"
-	cb.con.Memory.Mapper.MapTransportData(transport.TransportEntity{
+	cb.con.Memory.Gits.MapData(transport.TransportEntity{
+		ID:         0,
 		Type:       "AI",
 		Value:      "Cyberbrain",
-		Context:    "System",
+		Context:    cb.con.Memory.Scope("System"),
 		Properties: properties,
 	})
+	qry := gits.NewQuery().Update("AI").Match("Value", "==", "Cyberbrain").Match("Context", "==", cb.con.Memory.Scope("System")).Set("Properties.State", "Alive")
+	cb.con.Memory.Gits.Query().Execute(qry)
 }
 
 func (cb *Cyberbrain) createNecessaryEntities() {
@@ -230,7 +708,7 @@ func (cb *Cyberbrain) createNecessaryEntities() {
 		ID:         0,
 		Type:       "State",
 		Value:      "Open",
-		Context:    "System",
+		Context:    cb.con.Memory.Scope("System"),
 		Properties: make(map[string]string),
 	})
 	// create Assigned state
@@ -238,7 +716,23 @@ func (cb *Cyberbrain) createNecessaryEntities() {
 		ID:         0,
 		Type:       "State",
 		Value:      "Assigned",
-		Context:    "System",
+		Context:    cb.con.Memory.Scope("System"),
+		Properties: make(map[string]string),
+	})
+	// create Done state for finished jobs kept as history
+	cb.con.Memory.Gits.MapData(transport.TransportEntity{
+		ID:         0,
+		Type:       "State",
+		Value:      "Done",
+		Context:    cb.con.Memory.Scope("System"),
+		Properties: make(map[string]string),
+	})
+	// create Failed state, our dead letter queue
+	cb.con.Memory.Gits.MapData(transport.TransportEntity{
+		ID:         0,
+		Type:       "State",
+		Value:      "Failed",
+		Context:    cb.con.Memory.Scope("System"),
 		Properties: make(map[string]string),
 	})
 }
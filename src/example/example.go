@@ -6,6 +6,7 @@ import (
 	"github.com/voodooEntity/cyberbrain/src/system/cerebrum"
 	cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
 	"github.com/voodooEntity/cyberbrain/src/system/interfaces"
+	"context"
 	"net"
 	"strconv"
 	"time"
@@ -30,9 +31,15 @@ func (self *Example) SetMapper(mapper *cerebrum.Mapper) {
 }
 
 // Execute method mandatory
-func (self *Example) Execute(input transport.TransportEntity, requirement string, context string, jobUUID string) ([]transport.TransportEntity, error) {
+func (self *Example) Execute(input transport.TransportEntity, requirement string, dataContext string, jobUUID string) ([]transport.TransportEntity, error) {
+	return self.ExecuteContext(context.Background(), input, requirement, dataContext, jobUUID)
+}
+
+// ExecuteContext is optional and preferred over Execute. ctx is done
+// once the job timed out or has been cancelled
+func (self *Example) ExecuteContext(ctx context.Context, input transport.TransportEntity, requirement string, dataContext string, jobUUID string) ([]transport.TransportEntity, error) {
 	// resolve the ip
-	ips, err := net.LookupIP(input.Value)
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", input.Value)
 	if nil != err {
 		// if there was en error, return no data and the error
 		return []transport.TransportEntity{}, err
@@ -49,7 +56,7 @@ func (self *Example) Execute(input transport.TransportEntity, requirement string
 					ID:         -2,
 					Type:       "IP",
 					Value:      ipv4.String(),
-					Context:    context,
+					Context:    dataContext,
 					Properties: map[string]string{"protocol": "V4", "created": strconv.FormatInt(time.Now().Unix(), 10)},
 				}})
 		}
@@ -58,8 +65,12 @@ func (self *Example) Execute(input transport.TransportEntity, requirement string
 	// better ###
 	input.Properties = make(map[string]string)
 
-	//
-	time.Sleep(time.Second * 15)
+	// simulate some slow work, but stop as soon as the job is cancelled
+	select {
+	case <-ctx.Done():
+		return []transport.TransportEntity{}, context.Cause(ctx)
+	case <-time.After(time.Second * 15):
+	}
 
 	// now we return the enriched input data
 	// which will automatically be mapped onto
@@ -72,6 +83,7 @@ func (self *Example) GetConfig() transport.TransportEntity {
 	cfg := cfgb.NewConfig()
 	cfg.SetName("resolveIPFromDomain")
 	cfg.SetCategory("Pentest")
+	cfg.SetTimeout(time.Second * 30)
 
 	// define a dependency
 	alphaDependency := cfgb.NewStructure("Domain").SetPriority(cfgb.PRIORITY_PRIMARY).SetMode(cfgb.MODE_SET)
@@ -1,6 +1,11 @@
 package cerebrum
 
 import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/voodooEntity/gits/src/transport"
 	"github.com/voodooEntity/cyberbrain/src/system/interfaces"
 )
@@ -9,10 +14,20 @@ type Action struct {
 	name         string
 	categories   []transport.TransportEntity
 	dependencies []transport.TransportEntity
+	properties   map[string]string
 	instance     interfaces.ActionInterface
 	factory      func() interfaces.ActionInterface
 }
 
+// RetryPolicy describes how failed jobs of an action are retried,
+// parsed from the Retry.* properties of the action config
+type RetryPolicy struct {
+	MaxAttempts     int
+	Backoff         time.Duration
+	MaxBackoff      time.Duration
+	RetryableErrors []string
+}
+
 func NewAction() *Action {
 	return &Action{}
 }
@@ -44,6 +59,154 @@ func (self *Action) GetDependencyByName(name string) transport.TransportEntity {
 	return transport.TransportEntity{}
 }
 
+func (self *Action) SetProperties(properties map[string]string) *Action {
+	self.properties = properties
+	return self
+}
+
+func (self *Action) GetProperties() map[string]string {
+	return self.properties
+}
+
+// GetTimeout returns the execution timeout of the action, 0 means none
+func (self *Action) GetTimeout() time.Duration {
+	if val, err := strconv.ParseInt(self.properties["Timeout"], 10, 64); nil == err {
+		return time.Duration(val) * time.Millisecond
+	}
+	return 0
+}
+
+// GetPriority returns the priority jobs of this action are created with,
+// higher values are executed first
+func (self *Action) GetPriority() int {
+	if val, err := strconv.Atoi(self.properties["Priority"]); nil == err {
+		return val
+	}
+	return 0
+}
+
+// GetConcurrency returns the max amount of jobs of this action
+// being executed at the same time, 0 means unlimited
+func (self *Action) GetConcurrency() int {
+	if val, err := strconv.Atoi(self.properties["Concurrency"]); nil == err && 0 < val {
+		return val
+	}
+	return 0
+}
+
+// GetRateLimit returns the dependency alias or type forming the rate key
+// and the rate limit of the action. An empty key means no rate limit
+func (self *Action) GetRateLimit() (string, RateLimit) {
+	limit, err := strconv.Atoi(self.properties["RateLimit.Limit"])
+	if nil != err || 0 >= limit {
+		return "", RateLimit{}
+	}
+	per, _ := strconv.ParseInt(self.properties["RateLimit.Per"], 10, 64)
+	return self.properties["RateLimit.Key"], RateLimit{
+		Limit: limit,
+		Per:   time.Duration(per) * time.Millisecond,
+	}
+}
+
+// GetDelay returns the time jobs of this action wait after
+// their creation before they are due, 0 means none
+func (self *Action) GetDelay() time.Duration {
+	if val, err := strconv.ParseInt(self.properties["Delay"], 10, 64); nil == err {
+		return time.Duration(val) * time.Millisecond
+	}
+	return 0
+}
+
+// GetInterval returns the interval the action is re-run against every
+// input matching its dependencies, 0 means it is not periodic
+func (self *Action) GetInterval() time.Duration {
+	if val, err := strconv.ParseInt(self.properties["Interval"], 10, 64); nil == err {
+		return time.Duration(val) * time.Millisecond
+	}
+	return 0
+}
+
+// GetWitnessTTL returns how long the witness of a scheduled input keeps
+// it from being scheduled again, 0 means forever
+func (self *Action) GetWitnessTTL() time.Duration {
+	if val, err := strconv.ParseInt(self.properties["WitnessTTL"], 10, 64); nil == err {
+		return time.Duration(val) * time.Millisecond
+	}
+	return 0
+}
+
+// GetIdempotency returns the idempotency mode of the action and the
+// fields used by the Fields mode. An empty mode means the legacy signature
+func (self *Action) GetIdempotency() (string, []string) {
+	var fields []string
+	if "" != self.properties["Idempotency.Fields"] {
+		fields = strings.Split(self.properties["Idempotency.Fields"], ",")
+	}
+	return self.properties["Idempotency.Mode"], fields
+}
+
+// GetIdempotencyKey returns the idempotency key expressions of the action
+func (self *Action) GetIdempotencyKey() []string {
+	if "" == self.properties["Idempotency.Key"] {
+		return nil
+	}
+	return strings.Split(self.properties["Idempotency.Key"], ",")
+}
+
+func (self *Action) GetRetryPolicy() RetryPolicy {
+	policy := RetryPolicy{}
+	if val, err := strconv.Atoi(self.properties["Retry.MaxAttempts"]); nil == err {
+		policy.MaxAttempts = val
+	}
+	if val, err := strconv.ParseInt(self.properties["Retry.Backoff"], 10, 64); nil == err {
+		policy.Backoff = time.Duration(val) * time.Millisecond
+	}
+	if val, err := strconv.ParseInt(self.properties["Retry.MaxBackoff"], 10, 64); nil == err {
+		policy.MaxBackoff = time.Duration(val) * time.Millisecond
+	}
+	if val, ok := self.properties["Retry.Errors"]; ok && "" != val {
+		policy.RetryableErrors = strings.Split(val, "\n")
+	}
+	return policy
+}
+
+// ShouldRetry decides if a job that failed with err after the given amount
+// of attempts gets another try. Errors implementing RetryableErrorInterface
+// decide on their own, else the configured error patterns are checked
+func (p RetryPolicy) ShouldRetry(attempts int, err error) bool {
+	if attempts >= p.MaxAttempts {
+		return false
+	}
+	var classified interfaces.RetryableErrorInterface
+	if errors.As(err, &classified) {
+		return classified.Retryable()
+	}
+	if 0 == len(p.RetryableErrors) {
+		return true
+	}
+	for _, pattern := range p.RetryableErrors {
+		if strings.Contains(err.Error(), pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay returns the backoff to wait after the given amount of attempts
+func (p RetryPolicy) Delay(attempts int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if 0 < p.MaxBackoff && delay >= p.MaxBackoff {
+			break
+		}
+	}
+	if 0 < p.MaxBackoff && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
 func (self *Action) SetInstance(instance interfaces.ActionInterface) *Action {
 	self.instance = instance
 	return self
@@ -65,6 +228,14 @@ func (self *Action) GetCategories() []transport.TransportEntity {
 	return self.categories
 }
 
+// GetCategory returns the name of the category the action belongs to
+func (self *Action) GetCategory() string {
+	if 0 < len(self.categories) {
+		return self.categories[0].Value
+	}
+	return ""
+}
+
 func (self *Action) SetFactory(f func() interfaces.ActionInterface) *Action {
 	self.factory = f
 	return self
@@ -2,6 +2,9 @@ package cerebrum
 
 import (
 	"errors"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/voodooEntity/gits/src/query"
 	"github.com/voodooEntity/gits/src/transport"
@@ -24,22 +27,37 @@ func NewCortex(memoryInstance *Memory, logger *archivist.Archivist) *Cortex {
 	}
 }
 
-func (c *Cortex) RegisterAction(name string, factory func() interfaces.ActionInterface) {
+func (c *Cortex) RegisterAction(name string, factory func() interfaces.ActionInterface) error {
 	instance := factory()
 
+	// reject configs the scheduler can't handle before anything is stored
+	config := instance.GetConfig()
+	if err := c.validateConfig(name, config); nil != err {
+		c.log.Error("Rejected action ", name, " ", err)
+		return err
+	}
+
 	// store action config
-	c.memory.Mapper.MapTransportDataWithContext(instance.GetConfig(), "System")
+	c.memory.Mapper.MapTransportDataWithContext(config, c.memory.Scope("System"))
 
 	// Get the mapped categories
-	catQry := query.New().Read("Action").Match("Value", "==", name).To(query.New().Read("Category").TraverseOut(10))
+	catQry := query.New().Read("Action").Match("Value", "==", name).Match("Context", "==", c.memory.Scope("System")).To(query.New().Read("Category").TraverseOut(10))
 	categories := c.memory.Gits.Query().Execute(catQry)
 
 	// Get the mapped dependencies
-	depQry := query.New().Read("Action").Match("Value", "==", name).To(query.New().Read("Dependency").TraverseOut(10))
+	depQry := query.New().Read("Action").Match("Value", "==", name).Match("Context", "==", c.memory.Scope("System")).To(query.New().Read("Dependency").TraverseOut(10))
 	dependencies := c.memory.Gits.Query().Execute(depQry)
 
+	// reload the dependency structures including their parent structures
+	for key, dependency := range dependencies.Entities[0].ChildRelations {
+		if 0 < len(dependency.Target.ChildRelations) {
+			root := dependency.Target.ChildRelations[0].Target
+			dependencies.Entities[0].ChildRelations[key].Target.ChildRelations[0].Target = c.loadStructure(root.ID, map[int]bool{})
+		}
+	}
+
 	// create an action struct instance satisfied with the just mapped config and dependency data & the actual action instance itself
-	actionInstance := *NewAction().SetName(name).SetDependencies(dependencies.Entities[0].Children()).SetCategories(categories.Entities[0].Children()).SetInstance(instance).SetFactory(factory)
+	actionInstance := *NewAction().SetName(name).SetDependencies(dependencies.Entities[0].Children()).SetCategories(categories.Entities[0].Children()).SetProperties(dependencies.Entities[0].Properties).SetInstance(instance).SetFactory(factory)
 
 	// recursive filter all upcoming dependency types and map them onto lookup nodes for further faster processing
 	for _, val := range actionInstance.GetDependencies() {
@@ -53,7 +71,7 @@ func (c *Cortex) RegisterAction(name string, factory func() interfaces.ActionInt
 	c.register[name] = &actionInstance
 
 	c.log.Info("Registered action ", name)
-
+	return nil
 }
 
 func (c Cortex) GetAction(name string) (*Action, error) {
@@ -63,6 +81,20 @@ func (c Cortex) GetAction(name string) (*Action, error) {
 	return nil, errors.New("Action '" + name + "'not found in cortex")
 }
 
+// GetActions returns all registered actions ordered by name
+func (c Cortex) GetActions() []*Action {
+	names := make([]string, 0, len(c.register))
+	for name := range c.register {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	actions := make([]*Action, 0, len(names))
+	for _, name := range names {
+		actions = append(actions, c.register[name])
+	}
+	return actions
+}
+
 func (c Cortex) GetInstance(name string) (interfaces.ActionInterface, error) {
 	if val, ok := c.register[name]; ok {
 		return val.CreateInstance(), nil
@@ -79,7 +111,7 @@ func (c Cortex) mapDependencyRelationLookupNodes(entity transport.TransportEntit
 			Type:       "DependencyRelationLookup",
 			ID:         0,
 			Value:      val,
-			Context:    "System",
+			Context:    c.memory.Scope("System"),
 			Properties: make(map[string]string),
 			ChildRelations: []transport.TransportRelation{
 				transport.TransportRelation{
@@ -97,7 +129,7 @@ func (c Cortex) mapDependencyRelationLookupNodes(entity transport.TransportEntit
 func (c Cortex) rFindRelationStructures(entity transport.TransportEntity, relationStructures []string) []string {
 	if 0 < len(entity.ChildRelations) {
 		for _, childRelation := range entity.ChildRelations {
-			tmpRelString := entity.Value + "-" + childRelation.Target.Value
+			tmpRelString := relationStructureString(entity.Value, childRelation.Target.Properties["RelationContext"], childRelation.Target.Value)
 			add := true
 			for _, knownRelString := range relationStructures {
 				if knownRelString == tmpRelString {
@@ -107,19 +139,60 @@ func (c Cortex) rFindRelationStructures(entity transport.TransportEntity, relati
 			if add {
 				relationStructures = append(relationStructures, tmpRelString)
 			}
+			relationStructures = c.appendPathRelationStructures(relationStructures, entity.Value, childRelation.Target, childRelation.Target.Value)
 			relationStructures = c.rFindRelationStructures(childRelation.Target, relationStructures)
 		}
 	}
+	// parent structures are relations of the form parent-entity
+	for _, parentRelation := range entity.ParentRelations {
+		tmpRelString := relationStructureString(parentRelation.Target.Value, parentRelation.Target.Properties["RelationContext"], entity.Value)
+		if !util.StringInArray(relationStructures, tmpRelString) {
+			relationStructures = append(relationStructures, tmpRelString)
+		}
+		relationStructures = c.appendPathRelationStructures(relationStructures, parentRelation.Target.Value, parentRelation.Target, entity.Value)
+		relationStructures = c.rFindRelationStructures(parentRelation.Target, relationStructures)
+	}
 	return relationStructures
 }
 
+// appendPathRelationStructures adds the relation structures along the hops of a
+// path node, upper-via, via-via and via-lower, so new links on the path are found
+func (c Cortex) appendPathRelationStructures(relationStructures []string, upper string, node transport.TransportEntity, lower string) []string {
+	if "" == node.Properties["Path.Via"] {
+		return relationStructures
+	}
+	via := strings.Split(node.Properties["Path.Via"], ",")
+	var hops []string
+	for _, alpha := range via {
+		hops = append(hops, relationStructureString(upper, "", alpha), relationStructureString(alpha, "", lower))
+		for _, beta := range via {
+			hops = append(hops, relationStructureString(alpha, "", beta))
+		}
+	}
+	for _, hop := range hops {
+		if !util.StringInArray(relationStructures, hop) {
+			relationStructures = append(relationStructures, hop)
+		}
+	}
+	return relationStructures
+}
+
+// relationStructureString builds the lookup string of a relation structure,
+// parent-child for plain relations and parent-context-child for typed ones
+func relationStructureString(parentType string, context string, childType string) string {
+	if "" == context {
+		return parentType + "-" + childType
+	}
+	return parentType + "-" + context + "-" + childType
+}
+
 func (c Cortex) mapDependencyEntityLookupNodes(dependencyTypes []string, dependencyId int) {
 	for _, val := range dependencyTypes {
 		c.memory.Gits.MapData(transport.TransportEntity{
 			Type:       "DependencyEntityLookup",
 			ID:         0,
 			Value:      val,
-			Context:    "System",
+			Context:    c.memory.Scope("System"),
 			Properties: make(map[string]string),
 			ChildRelations: []transport.TransportRelation{
 				transport.TransportRelation{
@@ -147,11 +220,54 @@ func (c Cortex) rGetTypeList(typeList *[]string, data []transport.TransportEntit
 		// is Primary, because only a Primary should trigger a new job , just having an state:open should not trigger
 		// everything that might filter for a state. tho if its a Port with state open the Port should trigger. Thatfor
 		// dependency structures should be marked as Primary or Secondary
-		if !util.StringInArray(*typeList, val.Value) && "Structure" == val.Type && val.Properties["Type"] == "Primary" {
+		// absent nodes are always listed, their appearance revokes open jobs relying on the absence
+		if !util.StringInArray(*typeList, val.Value) && "Structure" == val.Type && (val.Properties["Type"] == "Primary" || val.Properties["Mode"] == "Absent") {
 			*typeList = append(*typeList, val.Value)
 		}
+		// new entities along the path of a primary path node can complete it
+		if "Structure" == val.Type && val.Properties["Type"] == "Primary" && "" != val.Properties["Path.Via"] {
+			for _, via := range strings.Split(val.Properties["Path.Via"], ",") {
+				if !util.StringInArray(*typeList, via) {
+					*typeList = append(*typeList, via)
+				}
+			}
+		}
 		if 0 < len(val.ChildRelations) {
 			c.rGetTypeList(typeList, val.Children())
 		}
+		if 0 < len(val.ParentRelations) {
+			c.rGetTypeList(typeList, val.Parents())
+		}
+	}
+}
+
+// loadStructure reads a dependency structure node with its children and parents.
+// Unlike traversing out of the dependency this also follows parent structures,
+// so patterns can point upwards from their root
+func (c Cortex) loadStructure(id int, visited map[int]bool) transport.TransportEntity {
+	visited[id] = true
+	qry := query.New().Read("Structure").Match("ID", "==", strconv.Itoa(id)).CanTo(query.New().Read("Structure")).CanFrom(query.New().Read("Structure"))
+	result := c.memory.Gits.Query().Execute(qry)
+	if 0 == result.Amount {
+		return transport.TransportEntity{}
+	}
+	node := result.Entities[0]
+	structure := transport.TransportEntity{
+		Type:       node.Type,
+		ID:         node.ID,
+		Value:      node.Value,
+		Context:    node.Context,
+		Properties: node.Properties,
+	}
+	for _, child := range node.Children() {
+		if !visited[child.ID] {
+			structure.ChildRelations = append(structure.ChildRelations, transport.TransportRelation{Target: c.loadStructure(child.ID, visited)})
+		}
+	}
+	for _, parent := range node.Parents() {
+		if !visited[parent.ID] {
+			structure.ParentRelations = append(structure.ParentRelations, transport.TransportRelation{Target: c.loadStructure(parent.ID, visited)})
+		}
 	}
+	return structure
 }
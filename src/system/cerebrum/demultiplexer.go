@@ -17,22 +17,41 @@ func (d *Demultiplexer) Parse(entity transport.TransportEntity) []transport.Tran
     var ret []transport.TransportEntity
     typeLookup := make(map[string]int)
     var typePointer [][]*transport.TransportEntity
-    if 0 < len(entity.ChildRelations) {
-		// collect children pointers grouped by type string
-		for key := range entity.ChildRelations {
-			if val, ok := typeLookup[entity.ChildRelations[key].Target.Type]; ok {
-				typePointer[val] = append(typePointer[val], &(entity.ChildRelations[key].Target))
+    // parent slots are kept apart from child slots of the same type
+    var typeIsParent []bool
+    // the relation every target was found through, so its context and
+    // properties survive the recombination
+    sourceRelation := make(map[*transport.TransportEntity]*transport.TransportRelation)
+    collect := func(relations []transport.TransportRelation, parent bool) {
+		for key := range relations {
+			lookupKey := relations[key].Target.Type
+			sourceRelation[&(relations[key].Target)] = &(relations[key])
+			if parent {
+				lookupKey = "<" + lookupKey
+			}
+			if val, ok := typeLookup[lookupKey]; ok {
+				typePointer[val] = append(typePointer[val], &(relations[key].Target))
 			} else {
-				typePointer = append(typePointer, []*transport.TransportEntity{&(entity.ChildRelations[key].Target)})
-				typeLookup[entity.ChildRelations[key].Target.Type] = len(typePointer) - 1
+				typePointer = append(typePointer, []*transport.TransportEntity{&(relations[key].Target)})
+				typeIsParent = append(typeIsParent, parent)
+				typeLookup[lookupKey] = len(typePointer) - 1
 			}
 		}
+    }
+    if 0 < len(entity.ChildRelations) || 0 < len(entity.ParentRelations) {
+		// collect children and parent pointers grouped by type string
+		collect(entity.ChildRelations, false)
+		collect(entity.ParentRelations, true)
 
 		// now we get the demultiplex each single one of them and build a second pointer list
 		demultiplexedTypePointer := make([][]*transport.TransportEntity, len(typePointer))
 		for typeId, typePointerList := range typePointer {
 			for _, singlePointer := range typePointerList {
-				demultiplexedTypePointer[typeId] = append(demultiplexedTypePointer[typeId], d.generateEntityPointerList(d.Parse(*singlePointer))...)
+				variants := d.generateEntityPointerList(d.Parse(*singlePointer))
+				for _, variant := range variants {
+					sourceRelation[variant] = sourceRelation[singlePointer]
+				}
+				demultiplexedTypePointer[typeId] = append(demultiplexedTypePointer[typeId], variants...)
 			}
 		}
 
@@ -41,20 +60,33 @@ func (d *Demultiplexer) Parse(entity transport.TransportEntity) []transport.Tran
 
         for _, recombinationSet := range recombinations {
             var tmpChildren []transport.TransportRelation
+            var tmpParents []transport.TransportRelation
             for key := range recombinationSet {
                 // Deep-copy the target entity to guarantee immutability across combinations
                 copied := d.deepCopyEntity(*recombinationSet[key])
-                tmpChildren = append(tmpChildren, transport.TransportRelation{
+                relation := transport.TransportRelation{
                     Target: copied,
-                })
+                }
+                if source, ok := sourceRelation[recombinationSet[key]]; ok {
+                    relation.Context = source.Context
+                    if nil != source.Properties {
+                        relation.Properties = util.CopyStringStringMap(source.Properties)
+                    }
+                }
+                if typeIsParent[key] {
+                    tmpParents = append(tmpParents, relation)
+                    continue
+                }
+                tmpChildren = append(tmpChildren, relation)
             }
             ret = append(ret, transport.TransportEntity{
-                Type:           entity.Type,
-                ID:             entity.ID,
-                Value:          entity.Value,
-                Context:        entity.Context,
-                Properties:     util.CopyStringStringMap(entity.Properties),
-                ChildRelations: tmpChildren,
+                Type:            entity.Type,
+                ID:              entity.ID,
+                Value:           entity.Value,
+                Context:         entity.Context,
+                Properties:      util.CopyStringStringMap(entity.Properties),
+                ChildRelations:  tmpChildren,
+                ParentRelations: tmpParents,
             })
         }
     } else {
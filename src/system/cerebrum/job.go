@@ -2,9 +2,10 @@ package cerebrum
 
 import (
 	"encoding/json"
+	"sort"
 	"strconv"
+	"time"
 
-	"github.com/voodooEntity/gits"
 	"github.com/voodooEntity/gits/src/query"
 	"github.com/voodooEntity/gits/src/transport"
 	gitsTypes "github.com/voodooEntity/gits/src/types"
@@ -13,10 +14,44 @@ import (
 )
 
 type Job struct {
-	data   transport.TransportEntity
-	memory *Memory
-	id     int
-	log    *archivist.Archivist
+	data         transport.TransportEntity
+	memory       *Memory
+	id           int
+	log          *archivist.Archivist
+	witness      string
+	priority     int
+	seedPriority string
+	category     string
+	limits       ConcurrencyLimits
+	rateKey      string
+	rateLimiter  *RateLimiter
+	rateLimit    RateLimit
+	notBefore    time.Time
+}
+
+// ConcurrencyLimits defines how many jobs of the same Action and of
+// the same Category may be assigned at the same time, 0 means unlimited
+type ConcurrencyLimits struct {
+	Action   int
+	Category int
+}
+
+// FailedJob is a read only view of a job in the Failed
+// state, our dead letter queue
+type FailedJob struct {
+	ID          int
+	Action      string
+	Requirement string
+	Error       string
+	Attempt     int
+	Neuron      string
+	Witness     string
+	Cancelled   bool
+	CancelCause string
+	Created     time.Time
+	Started     time.Time
+	Failed      time.Time
+	Input       transport.TransportEntity
 }
 
 func NewJob(memoryInstance *Memory, logger *archivist.Archivist) *Job {
@@ -26,10 +61,81 @@ func NewJob(memoryInstance *Memory, logger *archivist.Archivist) *Job {
 	}
 }
 
+// SetWitness stores the witness signature the job has been scheduled
+// with on the job so requeues can detect duplicates
+func (j *Job) SetWitness(witness string) *Job {
+	j.witness = witness
+	return j
+}
+
+// SetPriority sets the priority the job will be created with
+func (j *Job) SetPriority(priority int) *Job {
+	j.priority = priority
+	return j
+}
+
+// SetSeedPriority stores a priority explicitly given for the scheduled data.
+// It overrules the action priority and is inherited by follow up jobs
+func (j *Job) SetSeedPriority(priority string) *Job {
+	j.seedPriority = priority
+	return j
+}
+
+// SetCategory stores the category of the jobs action on the job
+// so concurrency limits can be checked without the cortex
+func (j *Job) SetCategory(category string) *Job {
+	j.category = category
+	return j
+}
+
+// SetNotBefore delays the job, it won't be picked up before the given time
+func (j *Job) SetNotBefore(notBefore time.Time) *Job {
+	j.notBefore = notBefore
+	return j
+}
+
+// SetRateKey stores the rate key the job is limited by
+func (j *Job) SetRateKey(rateKey string) *Job {
+	j.rateKey = rateKey
+	return j
+}
+
+// SetRateLimit sets the limiter and limit checked by AssignToRunner
+// for the RateKey of the job
+func (j *Job) SetRateLimit(rateLimiter *RateLimiter, rateLimit RateLimit) *Job {
+	j.rateLimiter = rateLimiter
+	j.rateLimit = rateLimit
+	return j
+}
+
+// SetLimits sets the concurrency limits checked by AssignToRunner
+func (j *Job) SetLimits(limits ConcurrencyLimits) *Job {
+	j.limits = limits
+	return j
+}
+
 func (j *Job) Create(action string, requirement string, input transport.TransportEntity) *Job {
 	jobProperties := make(map[string]string)
 	jobProperties["Action"] = action
 	jobProperties["Requirement"] = requirement
+	jobProperties["Created"] = util.Timestamp(time.Now())
+	jobProperties["Priority"] = strconv.Itoa(j.priority)
+	if "" != j.witness {
+		jobProperties["Witness"] = j.witness
+	}
+	if "" != j.category {
+		jobProperties["Category"] = j.category
+	}
+	if "" != j.rateKey {
+		jobProperties["RateKey"] = j.rateKey
+	}
+	if !j.notBefore.IsZero() {
+		jobProperties["NotBefore"] = util.Timestamp(j.notBefore)
+	}
+	if "" != j.seedPriority {
+		jobProperties["Priority"] = j.seedPriority
+		jobProperties["SeedPriority"] = j.seedPriority
+	}
 	inputProperties := make(map[string]string)
 	inputJson, err := json.Marshal(input)
 	if nil != err {
@@ -43,7 +149,7 @@ func (j *Job) Create(action string, requirement string, input transport.Transpor
 	mapped := j.memory.Mapper.MapTransportDataWithContextForceCreate(transport.TransportEntity{
 		ID:         -1,
 		Type:       "Job",
-		Context:    "system",
+		Context:    j.memory.Scope("System"),
 		Value:      util.UniqueID(),
 		Properties: jobProperties,
 		ChildRelations: []transport.TransportRelation{
@@ -51,24 +157,18 @@ func (j *Job) Create(action string, requirement string, input transport.Transpor
 				Target: transport.TransportEntity{
 					Type:       "Input",
 					ID:         -1,
-					Context:    "Cyberbrain",
+					Context:    j.memory.Scope("Cyberbrain"),
 					Value:      util.UniqueID(),
 					Properties: inputProperties,
 				},
 			},
 		},
-	}, "System")
-
-	openState := j.memory.Mapper.MapTransportData(transport.TransportEntity{
-		Type:       "State",
-		Value:      "Open",
-		Context:    "System",
-		ID:         0,
-		Properties: make(map[string]string),
-	})
+	}, j.memory.Scope("System"))
 
+	// link to our own open state, the State type may also
+	// exist in learned data or belong to another cyberbrain
 	linkQuery := query.New().Link("Job").Match("ID", "==", strconv.Itoa(mapped.ID)).To(
-		query.New().Find("State").Match("ID", "==", strconv.Itoa(openState.ID)),
+		query.New().Find("State").Match("Value", "==", "Open").Match("Context", "==", j.memory.Scope("System")),
 	)
 
 	j.memory.Gits.Query().Execute(linkQuery)
@@ -135,11 +235,34 @@ func (j *Job) AssignToRunner(runnerID int) bool {
 				j.log.Debug(archivist.DEBUG_LEVEL_INFO, "Runner tries to assign job that state is not Open", j.data.ID)
 				return false
 			}
+			// a retried job may not be due yet
+			if !IsDue(e.Properties, time.Now()) {
+				j.memory.Gits.Storage().EntityStorageMutex.Unlock()
+				j.memory.Gits.Storage().RelationStorageMutex.Unlock()
+				j.log.Debug(archivist.DEBUG_LEVEL_INFO, "Runner tries to assign job that is not due yet", j.data.ID)
+				return false
+			}
+			// get assigned state entity
+			assignedState, _ := j.memory.Gits.Storage().GetEntitiesByTypeAndValueUnsafe("State", "Assigned", "match", j.memory.Scope("System"))
+			// the action or category of the job may already run at its limit.
+			// since we hold the storage locks the counts are exact
+			if j.limitReachedUnsafe(jobTypeID, stateTypeID, assignedState[0].ID, e.Properties) {
+				j.memory.Gits.Storage().EntityStorageMutex.Unlock()
+				j.memory.Gits.Storage().RelationStorageMutex.Unlock()
+				j.log.Debug(archivist.DEBUG_LEVEL_INFO, "Runner tries to assign job that reached its concurrency limit", j.data.ID)
+				return false
+			}
+			// the target of the job may have been hit too often lately. this is
+			// the last check so a taken slot always belongs to an assigned job
+			if nil != j.rateLimiter && "" != e.Properties["RateKey"] && !j.rateLimiter.Take(e.Properties["RateKey"], j.rateLimit, time.Now()) {
+				j.memory.Gits.Storage().EntityStorageMutex.Unlock()
+				j.memory.Gits.Storage().RelationStorageMutex.Unlock()
+				j.log.Debug(archivist.DEBUG_LEVEL_INFO, "Runner tries to assign job whose rate limit is exhausted", j.data.ID)
+				return false
+			}
 			// detach open state from job
 			j.memory.Gits.Storage().DeleteRelationUnsafe(e.Type, e.ID, stateTypeID, openState.ID)
 			//gits.DeleteEntityUnsafe(openState.Type, openState.ID)
-			// get assigned state entity
-			assignedState, _ := j.memory.Gits.Storage().GetEntitiesByTypeAndValueUnsafe("State", "Assigned", "match", "System")
 			j.log.Debug(archivist.DEBUG_LEVEL_DUMP, "assigned state entity", assignedState)
 			// now we map the job to the assigned entity
 			j.memory.Gits.Storage().CreateRelationUnsafe(e.Type, e.ID, stateTypeID, assignedState[0].ID, gitsTypes.StorageRelation{
@@ -147,7 +270,7 @@ func (j *Job) AssignToRunner(runnerID int) bool {
 				SourceID:   e.ID,
 				TargetType: stateTypeID,
 				TargetID:   assignedState[0].ID,
-				Context:    "Cyberbrain",
+				Context:    j.memory.Scope("Cyberbrain"),
 				Properties: make(map[string]string),
 			})
 		}
@@ -155,14 +278,14 @@ func (j *Job) AssignToRunner(runnerID int) bool {
 
 	// finally we assign the job to the neuron
 	//runnerTypeID, _ := gits.GetTypeIdByStringUnsafe("Runner")
-	runnerEntity, _ := j.memory.Gits.Storage().GetEntitiesByTypeAndValueUnsafe("Neuron", strconv.Itoa(runnerID), "match", "Cyberbrain")
+	runnerEntity, _ := j.memory.Gits.Storage().GetEntitiesByTypeAndValueUnsafe("Neuron", strconv.Itoa(runnerID), "match", j.memory.Scope("Cyberbrain"))
 	j.log.Debug(archivist.DEBUG_LEVEL_DETAIL, "Map neuron to job", runnerEntity[0].Type, runnerEntity[0].ID, jobTypeID, e.ID)
 	j.memory.Gits.Storage().CreateRelationUnsafe(runnerEntity[0].Type, runnerEntity[0].ID, jobTypeID, e.ID, gitsTypes.StorageRelation{
 		SourceType: runnerEntity[0].Type,
 		SourceID:   runnerEntity[0].ID,
 		TargetType: jobTypeID,
 		TargetID:   e.ID,
-		Context:    "Cyberbrain",
+		Context:    j.memory.Scope("Cyberbrain"),
 		Properties: make(map[string]string),
 	})
 
@@ -171,6 +294,28 @@ func (j *Job) AssignToRunner(runnerID int) bool {
 	return true
 }
 
+// limitReachedUnsafe counts the assigned jobs sharing the Action or Category
+// of the given job properties. Caller has to hold the storage mutexes
+func (j *Job) limitReachedUnsafe(jobTypeID int, stateTypeID int, assignedStateID int, properties map[string]string) bool {
+	if 0 == j.limits.Action && 0 == j.limits.Category {
+		return false
+	}
+	sameAction, sameCategory := 0, 0
+	assignedJobs := j.memory.Gits.Storage().GetParentEntitiesByTargetTypeAndTargetIdAndSourceTypeUnsafe(stateTypeID, assignedStateID, jobTypeID, j.memory.Scope("System"))
+	for _, assignedJob := range assignedJobs {
+		if properties["Action"] == assignedJob.Properties["Action"] {
+			sameAction++
+		}
+		if "" != properties["Category"] && properties["Category"] == assignedJob.Properties["Category"] {
+			sameCategory++
+		}
+	}
+	if 0 < j.limits.Action && sameAction >= j.limits.Action {
+		return true
+	}
+	return 0 < j.limits.Category && sameCategory >= j.limits.Category
+}
+
 func (j *Job) GetState() string {
 	ret := j.memory.Gits.Query().Execute(query.New().Read("Job").Match("ID", "==", strconv.Itoa(j.data.ID)).To(query.New().Read("State")))
 	if 0 < ret.Amount {
@@ -180,12 +325,269 @@ func (j *Job) GetState() string {
 	return ""
 }
 
+// IsDue returns false if the job carries a NotBefore timestamp that lies in the future
+func IsDue(properties map[string]string, now time.Time) bool {
+	notBefore, ok := util.ParseTimestamp(properties["NotBefore"])
+	if !ok {
+		return true
+	}
+	return !now.Before(notBefore)
+}
+
+// SortJobsByPriority orders jobs by their priority, highest first. Every full
+// aging interval a job waited since its creation raises its priority by one
+// so low priority jobs are not starved. Equal priorities keep creation order
+func SortJobsByPriority(jobs []transport.TransportEntity, now time.Time, aging time.Duration) {
+	scores := make(map[int]int, len(jobs))
+	for _, job := range jobs {
+		priority, _ := strconv.Atoi(job.Properties["Priority"])
+		if 0 < aging {
+			if created, ok := util.ParseTimestamp(job.Properties["Created"]); ok && created.Before(now) {
+				priority += int(now.Sub(created) / aging)
+			}
+		}
+		scores[job.ID] = priority
+	}
+	sort.SliceStable(jobs, func(a, b int) bool {
+		if scores[jobs[a].ID] != scores[jobs[b].ID] {
+			return scores[jobs[a].ID] > scores[jobs[b].ID]
+		}
+		return jobs[a].ID < jobs[b].ID
+	})
+}
+
 func (self *Job) GetID() int {
 	return self.data.ID
 }
 
-func GetOpenJobs(gitsInstance *gits.Gits) transport.Transport {
-	qry := query.New().Read("State").Match("Value", "==", "Open").Match("Context", "==", "System").From(
-		query.New().Read("Job").Match("Context", "==", "System"))
-	return gitsInstance.Query().Execute(qry)
+// SetState detaches the job from its current state and links it
+// to the given state of our own scope
+func (j *Job) SetState(state string) {
+	unlinkQry := query.New().Unlink("Job").Match("ID", "==", strconv.Itoa(j.data.ID)).To(
+		query.New().Find("State").Match("Context", "==", j.memory.Scope("System")),
+	)
+	j.memory.Gits.Query().Execute(unlinkQry)
+
+	linkQry := query.New().Link("Job").Match("ID", "==", strconv.Itoa(j.data.ID)).To(
+		query.New().Find("State").Match("Value", "==", state).Match("Context", "==", j.memory.Scope("System")),
+	)
+	j.memory.Gits.Query().Execute(linkQry)
+}
+
+// DeadLetter moves the job to the Failed state. The input is kept
+// so the job can be inspected and requeued later on
+func (j *Job) DeadLetter(reason string, attempts int) {
+	j.SetProperty("Error", reason)
+	j.SetProperty("Attempt", strconv.Itoa(attempts))
+	j.SetProperty("FailedAt", util.Timestamp(time.Now()))
+	j.SetState("Failed")
+}
+
+// GetAttempts returns how many times execution of the job has failed so far
+func (j *Job) GetAttempts() int {
+	attempts, _ := strconv.Atoi(j.data.Properties["Attempt"])
+	return attempts
+}
+
+// Requeue puts a failed job back to Open and resets its attempts.
+// The last error is kept as LastError
+func (j *Job) Requeue() {
+	if "" != j.data.Properties["Error"] {
+		j.SetProperty("LastError", j.data.Properties["Error"])
+	}
+	j.SetProperty("Error", "")
+	j.SetProperty("Cancelled", "")
+	j.SetProperty("Attempt", "0")
+	j.SetProperty("NotBefore", "")
+	j.SetProperty("RequeuedAt", util.Timestamp(time.Now()))
+	j.SetState("Open")
+}
+
+// Revoke deletes the job if it is still Open. Returns false if it
+// has already been picked up by a neuron or doesn't exist anymore
+func (j *Job) Revoke() bool {
+	j.memory.Gits.Storage().EntityStorageMutex.Lock()
+	j.memory.Gits.Storage().RelationStorageMutex.Lock()
+	jobTypeID, err := j.memory.Gits.Storage().GetTypeIdByStringUnsafe("Job")
+	stateTypeID, stateErr := j.memory.Gits.Storage().GetTypeIdByStringUnsafe("State")
+	if nil != err || nil != stateErr {
+		j.memory.Gits.Storage().EntityStorageMutex.Unlock()
+		j.memory.Gits.Storage().RelationStorageMutex.Unlock()
+		return false
+	}
+	open := false
+	childRelations, _ := j.memory.Gits.Storage().GetChildRelationsBySourceTypeAndSourceIdUnsafe(jobTypeID, j.data.ID, "")
+	for _, childRelation := range childRelations {
+		if stateTypeID == childRelation.TargetType {
+			state, _ := j.memory.Gits.Storage().GetEntityByPathUnsafe(stateTypeID, childRelation.TargetID, "")
+			open = "Open" == state.Value && j.memory.Scope("System") == state.Context
+		}
+	}
+	if !open {
+		j.memory.Gits.Storage().EntityStorageMutex.Unlock()
+		j.memory.Gits.Storage().RelationStorageMutex.Unlock()
+		return false
+	}
+	// once the job is gone no neuron can pick it up anymore,
+	// so the input can be removed after releasing the locks
+	j.memory.Gits.Storage().DeleteEntityUnsafe(jobTypeID, j.data.ID)
+	j.memory.Gits.Storage().EntityStorageMutex.Unlock()
+	j.memory.Gits.Storage().RelationStorageMutex.Unlock()
+	for _, child := range j.data.Children() {
+		if "Input" == child.Type {
+			j.memory.Gits.Query().Execute(query.New().Delete("Input").Match("ID", "==", strconv.Itoa(child.ID)))
+		}
+	}
+	j.log.Debug(archivist.DEBUG_LEVEL_INFO, "Revoked open job", j.data.ID)
+	return true
+}
+
+// GetFailedJob builds the dead letter view of the job
+func (j *Job) GetFailedJob() FailedJob {
+	failed := FailedJob{
+		ID:          j.data.ID,
+		Action:      j.data.Properties["Action"],
+		Requirement: j.data.Properties["Requirement"],
+		Error:       j.data.Properties["Error"],
+		Neuron:      j.data.Properties["Neuron"],
+		Witness:     j.data.Properties["Witness"],
+		Cancelled:   "true" == j.data.Properties["Cancelled"],
+		CancelCause: j.data.Properties["CancelReason"],
+	}
+	failed.Attempt, _ = strconv.Atoi(j.data.Properties["Attempt"])
+	failed.Created, _ = util.ParseTimestamp(j.data.Properties["Created"])
+	failed.Started, _ = util.ParseTimestamp(j.data.Properties["Started"])
+	failed.Failed, _ = util.ParseTimestamp(j.data.Properties["FailedAt"])
+	for _, child := range j.data.Children() {
+		if "Input" == child.Type {
+			err := json.Unmarshal([]byte(child.Properties["Data"]), &failed.Input)
+			if nil != err {
+				j.log.Error("Job: "+j.data.Value+" - could not convert job input json back to struct data", child.Properties["Data"])
+			}
+		}
+	}
+	return failed
+}
+
+// SetProperty updates a single property on the job entity
+func (j *Job) SetProperty(key string, value string) {
+	qry := query.New().Update("Job").Match("ID", "==", strconv.Itoa(j.data.ID)).Set("Properties."+key, value)
+	j.memory.Gits.Query().Execute(qry)
+}
+
+// Delete removes the job and its input. Relations
+// are removed alongside by gits
+func (j *Job) Delete() {
+	j.DeleteInput()
+	qry := query.New().Delete("Job").Match("ID", "==", strconv.Itoa(j.data.ID))
+	j.memory.Gits.Query().Execute(qry)
+}
+
+// DeleteInput removes only the input of the job, which
+// keeps the job itself as history
+func (j *Job) DeleteInput() {
+	inputQry := query.New().Read("Input").From(
+		query.New().Read("Job").Match("ID", "==", strconv.Itoa(j.data.ID)),
+	)
+	dat := j.memory.Gits.Query().Execute(inputQry)
+	for _, input := range dat.Entities {
+		qry := query.New().Delete("Input").Match("ID", "==", strconv.Itoa(input.ID))
+		j.memory.Gits.Query().Execute(qry)
+	}
+}
+
+func GetOpenJobs(memoryInstance *Memory) transport.Transport {
+	qry := query.New().Read("State").Match("Value", "==", "Open").Match("Context", "==", memoryInstance.Scope("System")).From(
+		query.New().Read("Job").Match("Context", "==", memoryInstance.Scope("System")))
+	return memoryInstance.Gits.Query().Execute(qry)
+}
+
+
+// CountAssignedJobs returns the amount of assigned jobs per Action and per Category
+func CountAssignedJobs(memoryInstance *Memory) (map[string]int, map[string]int) {
+	byAction := make(map[string]int)
+	byCategory := make(map[string]int)
+	qry := query.New().Read("State").Match("Value", "==", "Assigned").Match("Context", "==", memoryInstance.Scope("System")).From(
+		query.New().Read("Job").Match("Context", "==", memoryInstance.Scope("System")))
+	ret := memoryInstance.Gits.Query().Execute(qry)
+	if 0 < ret.Amount {
+		for _, job := range ret.Entities[0].Parents() {
+			byAction[job.Properties["Action"]]++
+			if "" != job.Properties["Category"] {
+				byCategory[job.Properties["Category"]]++
+			}
+		}
+	}
+	return byAction, byCategory
+}
+
+// GetRunningJobIDs returns the IDs of all jobs that are currently
+// linked to a neuron and therefore still being executed
+func GetRunningJobIDs(memoryInstance *Memory) []int {
+	qry := query.New().Read("Neuron").Match("Context", "==", memoryInstance.Scope("Cyberbrain")).To(query.New().Read("Job"))
+	ret := memoryInstance.Gits.Query().Execute(qry)
+	ids := make([]int, 0)
+	for _, neuron := range ret.Entities {
+		for _, job := range neuron.Children() {
+			ids = append(ids, job.ID)
+		}
+	}
+	return ids
+}
+
+// GetOrphanedJobIDs returns the IDs of all jobs that are Assigned but still
+// hold their input, which means they never finished. Finished jobs that are
+// kept as history have no input anymore
+func GetOrphanedJobIDs(memoryInstance *Memory) []int {
+	qry := query.New().Read("Job").Match("Context", "==", memoryInstance.Scope("System")).To(
+		query.New().Read("State").Match("Value", "==", "Assigned").Match("Context", "==", memoryInstance.Scope("System")),
+	).To(
+		query.New().Read("Input"),
+	)
+	ret := memoryInstance.Gits.Query().Execute(qry)
+	ids := make([]int, 0)
+	for _, job := range ret.Entities {
+		ids = append(ids, job.ID)
+	}
+	return ids
+}
+
+// RemoveNeurons deletes all Neuron entities of our scope. Used on startup
+// to clear neurons a previous run left behind
+func RemoveNeurons(memoryInstance *Memory) int {
+	qry := query.New().Delete("Neuron").Match("Context", "==", memoryInstance.Scope("Cyberbrain"))
+	ret := memoryInstance.Gits.Query().Execute(qry)
+	return ret.Amount
+}
+
+// GetJobIDsByState returns the IDs of all jobs of our scope in the given state
+func GetJobIDsByState(memoryInstance *Memory, state string) []int {
+	qry := query.New().Read("Job").Match("Context", "==", memoryInstance.Scope("System")).To(
+		query.New().Read("State").Match("Value", "==", state).Match("Context", "==", memoryInstance.Scope("System")),
+	)
+	ret := memoryInstance.Gits.Query().Execute(qry)
+	ids := make([]int, 0)
+	for _, job := range ret.Entities {
+		ids = append(ids, job.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// HasActiveJobWithWitness checks if there is an Open or Assigned job
+// other than the excluded one that has been scheduled with the given witness
+func HasActiveJobWithWitness(memoryInstance *Memory, witness string, excludeID int) bool {
+	if "" == witness {
+		return false
+	}
+	qry := query.New().Read("Job").Match("Context", "==", memoryInstance.Scope("System")).Match("Properties.Witness", "==", witness).To(
+		query.New().Read("State").Match("Value", "in", "Open,Assigned").Match("Context", "==", memoryInstance.Scope("System")),
+	)
+	ret := memoryInstance.Gits.Query().Execute(qry)
+	for _, job := range ret.Entities {
+		if excludeID != job.ID {
+			return true
+		}
+	}
+	return false
 }
@@ -1,6 +1,8 @@
 package cerebrum
 
 import (
+	"strings"
+
 	"github.com/voodooEntity/gits"
 	"github.com/voodooEntity/gits/src/storage"
 	"github.com/voodooEntity/gits/src/transport"
@@ -241,6 +243,8 @@ func (m *Mapper) mapRecursive(entity transport.TransportEntity, relatedType int,
 	// a relation
 	createdRelation := false
 	if relatedType != -1 && relatedID != -1 {
+		// the relation given in the data may be typed by its context and properties
+		relationContext, relationProperties := m.relationData(ctx)
 		// lets create the relation to our parent
 		if storage.DIRECTION_CHILD == direction {
 			// first we make sure the relation doesnt already exist (because we allow mapped existing data inside a to map json)
@@ -250,10 +254,14 @@ func (m *Mapper) mapRecursive(entity transport.TransportEntity, relatedType int,
 					SourceID:   relatedID,
 					TargetType: TypeID,
 					TargetID:   mapID,
+					Context:    relationContext,
+					Properties: relationProperties,
 					Version:    1,
 				}
 				m.gits.Storage().CreateRelationUnsafe(relatedType, relatedID, TypeID, mapID, tmpRelation)
 				createdRelation = true
+			} else {
+				createdRelation = m.handleExistingRelation(relatedType, relatedID, TypeID, mapID, relationContext, relationProperties)
 			}
 		} else if storage.DIRECTION_PARENT == direction {
 			// first we make sure the relation doesnt already exist (because we allow mapped existing data inside a to map json)
@@ -264,15 +272,19 @@ func (m *Mapper) mapRecursive(entity transport.TransportEntity, relatedType int,
 					SourceID:   mapID,
 					TargetType: relatedType,
 					TargetID:   relatedID,
+					Context:    relationContext,
+					Properties: relationProperties,
 					Version:    1,
 				}
 				m.gits.Storage().CreateRelationUnsafe(TypeID, mapID, relatedType, relatedID, tmpRelation)
 				createdRelation = true
+			} else {
+				createdRelation = m.handleExistingRelation(TypeID, mapID, relatedType, relatedID, relationContext, relationProperties)
 			}
 		}
 	}
 
-	// if we created a relation we gonne mark the relation with bmap so we can identify it late ron in the scheduler in terms of structural mapping
+	// if we created a relation, or added a context to an existing one, we gonne mark the relation with bmap so we can identify it late ron in the scheduler in terms of structural mapping
  if createdRelation {
         // Always mark the created relation with bMap so the scheduler treats relation-only
         // additions as part of the delta that can trigger lookups and enrichment.
@@ -289,6 +301,60 @@ func (m *Mapper) mapRecursive(entity transport.TransportEntity, relatedType int,
 	return entity
 }
 
+// relationData returns the context and a copy of the properties of the relation
+// we are mapping through, the internal bMap flag is not persisted
+func (m *Mapper) relationData(ctx *RecursiveMapCtx) (string, map[string]string) {
+	properties := map[string]string{}
+	if nil == ctx || nil == ctx.SourceRelation {
+		return "", properties
+	}
+	for key, value := range ctx.SourceRelation.Properties {
+		if "bMap" != key && "Contexts" != key {
+			properties[key] = value
+		}
+	}
+	return ctx.SourceRelation.Context, properties
+}
+
+// handleExistingRelation merges the context and properties of the mapped data into
+// an already existing relation. gits keeps a single relation per entity pair, so
+// the first context stays the relation Context and every further one is added to
+// the comma separated Contexts property instead of replacing it. Returns true if
+// a context has been added, which is a delta for patterns requiring that context.
+// Property updates alone don't trigger the scheduler
+func (m *Mapper) handleExistingRelation(srcType int, srcID int, targetType int, targetID int, context string, properties map[string]string) bool {
+	existing, err := m.gits.Storage().GetRelationUnsafe(srcType, srcID, targetType, targetID)
+	if nil != err {
+		return false
+	}
+	existing.Properties = util.CopyStringStringMap(existing.Properties)
+	updated := false
+	addedContext := false
+	if "" != context && !util.StringInArray(util.RelationContexts(existing.Context, existing.Properties), context) {
+		if "" == existing.Context {
+			existing.Context = context
+		} else {
+			existing.Properties["Contexts"] = strings.Join(append(util.RelationContexts(existing.Context, existing.Properties), context), ",")
+		}
+		updated = true
+		addedContext = true
+	}
+	for key, value := range properties {
+		if existingValue, exists := existing.Properties[key]; !exists || existingValue != value {
+			existing.Properties[key] = value
+			updated = true
+		}
+	}
+	if !updated {
+		return false
+	}
+	if _, err := m.gits.Storage().UpdateRelationUnsafe(srcType, srcID, targetType, targetID, existing); nil != err {
+		m.log.Error("Could not update relation ", err)
+		return false
+	}
+	return addedContext
+}
+
 func (m *Mapper) getRelatedEntityWithTypeAndValue(entity transport.TransportEntity, entityTypeID int, relatedType int, relatedID int, direction int) (transport.TransportEntity, bool, error) {
 	m.log.Debug(archivist.DEBUG_LEVEL_MAX, "Trying to find related entity by type, value and related addr", entity, entityTypeID, relatedType, relatedID, direction)
 	entities, err := m.gits.Storage().GetEntitiesByTypeAndValueUnsafe(entity.Type, entity.Value, "match", "")
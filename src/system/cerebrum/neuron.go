@@ -1,16 +1,20 @@
 package cerebrum
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/voodooEntity/gits"
 	"github.com/voodooEntity/gits/src/query"
 	"github.com/voodooEntity/gits/src/transport"
 	"github.com/voodooEntity/cyberbrain/src/system/archivist"
+	"github.com/voodooEntity/cyberbrain/src/system/interfaces"
 	"github.com/voodooEntity/cyberbrain/src/system/util"
 )
 
@@ -31,8 +35,31 @@ type Neuron struct {
 	activity *Activity
 	log      *archivist.Archivist
 	history  bool
+	aging    time.Duration
+	// max concurrent jobs per category
+	categoryLimits map[string]int
+	retired  chan struct{}
+	retire   sync.Once
+	// guards the cancel func of the job currently executed
+	jobMutex   sync.Mutex
+	runningJob int
+	cancelJob  context.CancelCauseFunc
+	// parent of the job contexts, running jobs are aborted once it is done
+	jobContext context.Context
 }
 
+// ErrJobTimeout is the cancellation cause of a job that
+// exceeded the timeout configured on its action
+var ErrJobTimeout = errors.New("job execution timed out")
+
+// ErrJobCancelled is the cancellation cause of a job
+// that has been cancelled by CancelJob
+var ErrJobCancelled = errors.New("job has been cancelled")
+
+// ErrJobAborted is the cancellation cause of a job that has been
+// interrupted because the cyberbrain stopped. Aborted jobs are reopened
+var ErrJobAborted = errors.New("job has been aborted")
+
 //   - - - - - - - - - - - - - - - - - - - - - -
 //     Interface definitions placed here
 //     to prevent cyclic imports - ###
@@ -49,6 +76,13 @@ type ActionExtendLoggerInterface interface {
 	SetLogger(*archivist.Archivist)
 }
 
+// ActionExecuteContextInterface is the v2 of ActionInterface.Execute. If an
+// action implements it, it is executed with a context that is done once the
+// job timed out or has been cancelled
+type ActionExecuteContextInterface interface {
+	ExecuteContext(context.Context, transport.TransportEntity, string, string, string) ([]transport.TransportEntity, error)
+}
+
 func NewNeuron(id int, cortexInstance *Cortex, memoryInstance *Memory, activityInstance *Activity, logger *archivist.Archivist) *Neuron {
 	logger.Info("Creating neuron", id)
 	properties := make(map[string]string)
@@ -57,23 +91,58 @@ func NewNeuron(id int, cortexInstance *Cortex, memoryInstance *Memory, activityI
 		ID:         -1,
 		Type:       "Neuron",
 		Value:      strconv.Itoa(id),
-		Context:    "Cyberbrain",
+		Context:    memoryInstance.Scope("Cyberbrain"),
 		Properties: properties,
 	})
 
 	return &Neuron{
-		id:       id,
-		intercom: [2]chan string{make(chan string, INTERCOM_BUFF_SIZE), make(chan string, INTERCOM_BUFF_SIZE)},
-		cortex:   cortexInstance,
-		memory:   memoryInstance,
-		activity: activityInstance,
-		log:      logger,
-		history:  false,
+		id:         id,
+		intercom:   [2]chan string{make(chan string, INTERCOM_BUFF_SIZE), make(chan string, INTERCOM_BUFF_SIZE)},
+		cortex:     cortexInstance,
+		memory:     memoryInstance,
+		activity:   activityInstance,
+		log:        logger,
+		history:    false,
+		retired:    make(chan struct{}),
+		jobContext: context.Background(),
+	}
+}
+
+// SetJobContext sets the context the job contexts are derived from. Once it
+// is done the job currently executed is aborted
+func (n *Neuron) SetJobContext(ctx context.Context) {
+	n.jobContext = ctx
+}
+
+func (n *Neuron) GetID() int {
+	return n.id
+}
+
+// Retire signals the neuron to exit its loop. A job that is currently
+// executed will be finished before the neuron is marked Dead
+func (n *Neuron) Retire() {
+	n.retire.Do(func() {
+		close(n.retired)
+	})
+}
+
+func (n *Neuron) isRetired() bool {
+	select {
+	case <-n.retired:
+		return true
+	default:
+		return false
 	}
 }
 
 func (n *Neuron) Loop() {
-	for util.IsAlive(n.memory.Gits) {
+	n.LoopContext(context.Background())
+}
+
+// LoopContext works like Loop but also exits once ctx is done. A job that
+// already has been assigned is always finished before the neuron exits.
+func (n *Neuron) LoopContext(ctx context.Context) {
+	for util.IsAlive(n.memory.Gits, n.memory.Ident) && nil == ctx.Err() && !n.isRetired() {
 		n.log.Debug(archivist.DEBUG_LEVEL_MAX, "Neuron looping id: ", n.id)
 		// lets try to assign a job
 		if n.FindJob() {
@@ -90,25 +159,59 @@ func (n *Neuron) Loop() {
 			}
 		} else {
 			//			time.Sleep(1000000000)
-			time.Sleep(100 * time.Millisecond)
+			select {
+			case <-ctx.Done():
+			case <-n.retired:
+			case <-time.After(100 * time.Millisecond):
+			}
 		}
 		//time.Sleep(time.Second * 4)
 	}
 	n.ChangeState("Dead")
+	if n.isRetired() {
+		n.log.Info("Neuron has been retired, exiting", n.id)
+		return
+	}
 	n.log.Info("Cyberbrain has been shutdown, neuron exiting")
 }
 
 func (n *Neuron) FindJob() bool {
 	// query can be optimized by joining ###todo
-	jobList := GetOpenJobs(n.memory.Gits)
+	jobList := GetOpenJobs(n.memory)
 	n.log.Debug(archivist.DEBUG_LEVEL_MAX, "Open Jobs found", jobList)
 	// if there are any jobs
 	if 0 < jobList.Amount {
-		// iterate through them
-		for _, jobEntity := range jobList.Entities[0].Parents() {
+		// iterate through them, most important first
+		now := time.Now()
+		jobs := jobList.Entities[0].Parents()
+		SortJobsByPriority(jobs, now, n.aging)
+		runningByAction, runningByCategory := CountAssignedJobs(n.memory)
+		for _, jobEntity := range jobs {
+			// skip jobs that wait for a retry
+			if !IsDue(jobEntity.Properties, now) {
+				continue
+			}
+			// skip jobs whose action or category is at its limit, the
+			// exact check is done again while assigning
+			limits := n.getLimits(jobEntity.Properties)
+			if 0 < limits.Action && runningByAction[jobEntity.Properties["Action"]] >= limits.Action {
+				continue
+			}
+			if 0 < limits.Category && runningByCategory[jobEntity.Properties["Category"]] >= limits.Category {
+				continue
+			}
+			// defer jobs whose target has been hit too often lately
+			rateLimit, rateLimited := n.getRateLimit(jobEntity.Properties)
+			if rateLimited && !n.activity.RateLimiter.Allow(jobEntity.Properties["RateKey"], rateLimit, now) {
+				continue
+			}
 			// load the full job data as instance of job struct
 			newJob := Load(jobEntity.ID, n.memory, n.log)
 			if nil != newJob {
+				newJob.SetLimits(limits)
+				if rateLimited {
+					newJob.SetRateLimit(n.activity.RateLimiter, rateLimit)
+				}
 				// finally assign the job
 				ok := n.AssignJob(newJob)
 				if ok {
@@ -122,7 +225,7 @@ func (n *Neuron) FindJob() bool {
 }
 
 func (n *Neuron) ExecuteJob() ([]transport.TransportEntity, error) {
-	qry := query.New().Read("Neuron").Match("Value", "==", strconv.Itoa(n.id)).To(query.New().Read("Job").To(query.New().Read("Input")))
+	qry := query.New().Read("Neuron").Match("Value", "==", strconv.Itoa(n.id)).Match("Context", "==", n.memory.Scope("Cyberbrain")).To(query.New().Read("Job").To(query.New().Read("Input")))
 	ret := n.memory.Gits.Query().Execute(qry)
 
 	if 0 == ret.Amount {
@@ -171,9 +274,9 @@ func (n *Neuron) ExecuteJob() ([]transport.TransportEntity, error) {
 	}
 
 	// and finally execute it
-	results, err := actionInstance.Execute(inputEntity, ret.Entities[0].Children()[0].Properties["Requirement"], "Neuron", ret.Entities[0].Children()[0].Value)
+	results, err := n.executeWithContext(actionInstance, jobAction, ret.Entities[0].Children()[0], inputEntity)
 	if nil != err {
-		return []transport.TransportEntity{}, errors.New("Job: " + ret.Entities[0].Children()[0].Value + " execution failed with error " + err.Error())
+		return []transport.TransportEntity{}, fmt.Errorf("Job: %s execution failed with error %w", ret.Entities[0].Children()[0].Value, err)
 	}
 	n.log.Info("Job: " + ret.Entities[0].Children()[0].Value + " finished successfully")
 	// temporary debug ###
@@ -186,6 +289,68 @@ func (n *Neuron) ExecuteJob() ([]transport.TransportEntity, error) {
 	return results, nil
 }
 
+// executeWithContext runs the action bound to a context derived from the job
+// context of the neuron carrying the timeout of the action. Actions
+// implementing ActionExecuteContextInterface receive the context, v1 actions
+// can't be interrupted so they are run to the end and their results are
+// dropped if the context is done meanwhile.
+func (n *Neuron) executeWithContext(actionInstance interfaces.ActionInterface, jobAction *Action, jobEntity transport.TransportEntity, inputEntity transport.TransportEntity) ([]transport.TransportEntity, error) {
+	ctx, cancel := context.WithCancelCause(n.jobContext)
+	defer cancel(nil)
+	if timeout := jobAction.GetTimeout(); 0 < timeout {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, timeout, ErrJobTimeout)
+		defer cancelTimeout()
+	}
+
+	// make the job cancellable from the outside
+	n.jobMutex.Lock()
+	n.runningJob = jobEntity.ID
+	n.cancelJob = cancel
+	n.jobMutex.Unlock()
+	defer func() {
+		n.jobMutex.Lock()
+		n.runningJob = 0
+		n.cancelJob = nil
+		n.jobMutex.Unlock()
+	}()
+
+	requirement := jobEntity.Properties["Requirement"]
+	var results []transport.TransportEntity
+	var err error
+	if contextAction, ok := actionInstance.(ActionExecuteContextInterface); ok {
+		results, err = contextAction.ExecuteContext(ctx, inputEntity, requirement, "Neuron", jobEntity.Value)
+	} else {
+		results, err = actionInstance.Execute(inputEntity, requirement, "Neuron", jobEntity.Value)
+	}
+	if nil != ctx.Err() {
+		n.log.Warning("Job: "+jobEntity.Value+" interrupted", context.Cause(ctx).Error())
+		return []transport.TransportEntity{}, interruptCause(ctx)
+	}
+	return results, err
+}
+
+// interruptCause returns why the job context is done. Anything but a
+// timeout or a cancellation comes from the job context of the neuron
+func interruptCause(ctx context.Context) error {
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrJobTimeout) || errors.Is(cause, ErrJobCancelled) {
+		return cause
+	}
+	return ErrJobAborted
+}
+
+// CancelJob cancels the given job if this neuron is currently executing it
+func (n *Neuron) CancelJob(jobID int) bool {
+	n.jobMutex.Lock()
+	defer n.jobMutex.Unlock()
+	if nil == n.cancelJob || jobID != n.runningJob {
+		return false
+	}
+	n.cancelJob(ErrJobCancelled)
+	return true
+}
+
 func (n *Neuron) AssignJob(newJob *Job) bool {
 	// update runners status to Assigning...
 	n.ChangeState("Assigning")
@@ -211,6 +376,10 @@ func (n *Neuron) AssignJob(newJob *Job) bool {
 		"Value",
 		"==",
 		strconv.Itoa(n.id),
+	).Match(
+		"Context",
+		"==",
+		n.memory.Scope("Cyberbrain"),
 	).Link("Job").Match(
 		"ID",
 		"==",
@@ -218,6 +387,10 @@ func (n *Neuron) AssignJob(newJob *Job) bool {
 	)
 	n.memory.Gits.Query().Execute(qry)
 
+	// remember who is working on it since when
+	newJob.SetProperty("Neuron", strconv.Itoa(n.id))
+	newJob.SetProperty("Started", util.Timestamp(time.Now()))
+
 	n.job = *newJob
 
 	return true
@@ -240,6 +413,10 @@ func (n *Neuron) ChangeState(state string) {
 		"Value",
 		"==",
 		strconv.Itoa(n.id),
+	).Match(
+		"Context",
+		"==",
+		n.memory.Scope("Cyberbrain"),
 	).Set(
 		"Properties.State",
 		state,
@@ -248,6 +425,27 @@ func (n *Neuron) ChangeState(state string) {
 }
 
 func (n *Neuron) FinishJobSuccess(results []transport.TransportEntity) {
+	qry := query.New().Read("Neuron").Match(
+		"Value",
+		"==",
+		strconv.Itoa(n.id),
+	).Match(
+		"Context",
+		"==",
+		n.memory.Scope("Cyberbrain"),
+	).To(query.New().Read("Job"))
+
+	runnerWithJob := n.memory.Gits.Query().Execute(qry)
+	// the job may have been deleted while it was running
+	if 0 == runnerWithJob.Amount {
+		n.log.Warning("Finished job is gone, discarding its results", n.id)
+		n.ChangeState("Searching")
+		return
+	}
+	jobId := runnerWithJob.Entities[0].Children()[0].ID
+	// follow up jobs inherit an explicitly given priority
+	seedPriority := runnerWithJob.Entities[0].Children()[0].Properties["SeedPriority"]
+
     // going through the results
     for _, result := range results {
         n.log.Debug(archivist.DEBUG_LEVEL_MAX, "Mapping result from job", result)
@@ -260,19 +458,11 @@ func (n *Neuron) FinishJobSuccess(results []transport.TransportEntity) {
         n.log.Debug(archivist.DEBUG_LEVEL_DETAIL, "Running freshly mapped job return with scheduler: "+string(jsonData))
         // scheduling: log origin neuron and signature before scheduling
         n.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling NEURON ", n.id, " scheduling result signature=", util.GenerateSignature(mappedResult))
-        n.activity.Scheduler.Run(result, n.cortex)
+        n.activity.Scheduler.RunWithPriority(result, n.cortex, seedPriority)
     }
 
-	qry := query.New().Read("Neuron").Match(
-		"Value",
-		"==",
-		strconv.Itoa(n.id),
-	).To(query.New().Read("Job"))
-
-	runnerWithJob := n.memory.Gits.Query().Execute(qry)
-	jobId := runnerWithJob.Entities[0].Children()[0].ID
 	n.log.Debug(archivist.DEBUG_LEVEL_DUMP, "Detaching job from neuron", runnerWithJob)
-	qry = query.New().Unlink("Neuron").Match("Value", "==", strconv.Itoa(n.id)).To(
+	qry = query.New().Unlink("Neuron").Match("Value", "==", strconv.Itoa(n.id)).Match("Context", "==", n.memory.Scope("Cyberbrain")).To(
 		query.New().Find("Job").Match("ID", "==", strconv.Itoa(jobId)),
 	)
 	n.memory.Gits.Query().Execute(qry)
@@ -280,7 +470,7 @@ func (n *Neuron) FinishJobSuccess(results []transport.TransportEntity) {
 	if !n.history {
 		n.deleteJobAndInput(jobId)
 	} else {
-		n.deleteJobInput(jobId)
+		n.finishJobWithHistory(jobId)
 	}
 
 	n.ChangeState("Searching")
@@ -292,53 +482,145 @@ func (n *Neuron) FinishJobError(err error) {
 		"Value",
 		"==",
 		strconv.Itoa(n.id),
+	).Match(
+		"Context",
+		"==",
+		n.memory.Scope("Cyberbrain"),
 	).To(query.New().Read("Job"))
 
 	runnerWithJob := n.memory.Gits.Query().Execute(qry)
+	// the job may have been deleted while it was running
+	if 0 == runnerWithJob.Amount {
+		n.log.Warning("Failed job is gone, nothing to record", n.id)
+		n.ChangeState("Searching")
+		return
+	}
 	jobId := runnerWithJob.Entities[0].Children()[0].ID
 	n.log.Debug(archivist.DEBUG_LEVEL_DUMP, "Detaching job from neuron", runnerWithJob)
-	qry = query.New().Unlink("Neuron").Match("Value", "==", strconv.Itoa(n.id)).To(
+	qry = query.New().Unlink("Neuron").Match("Value", "==", strconv.Itoa(n.id)).Match("Context", "==", n.memory.Scope("Cyberbrain")).To(
 		query.New().Find("Job").Match("ID", "==", strconv.Itoa(jobId)),
 	)
 
 	n.memory.Gits.Query().Execute(qry)
 
-	if !n.history {
-		n.deleteJobAndInput(jobId)
-	} else {
-		n.deleteJobInput(jobId)
+	job := Load(jobId, n.memory, n.log)
+	if nil == job {
+		n.ChangeState("Searching")
+		return
+	}
+	attempts := job.GetAttempts() + 1
+
+	// record why the execution has been interrupted
+	if errors.Is(err, ErrJobTimeout) {
+		job.SetProperty("CancelReason", ErrJobTimeout.Error())
+	} else if errors.Is(err, ErrJobCancelled) {
+		job.SetProperty("CancelReason", ErrJobCancelled.Error())
+	} else if errors.Is(err, ErrJobAborted) {
+		job.SetProperty("CancelReason", ErrJobAborted.Error())
+	}
+
+	// aborted jobs didn't fail on their own, so they
+	// are reopened without counting the attempt
+	if errors.Is(err, ErrJobAborted) {
+		job.SetState("Open")
+		n.ChangeState("Searching")
+		return
+	}
+
+	// cancelled jobs are never retried
+	if errors.Is(err, ErrJobCancelled) {
+		job.SetProperty("Cancelled", "true")
+		job.DeadLetter(err.Error(), attempts)
+		n.ChangeState("Searching")
+		return
+	}
+
+	// lets see if the action wants the job to be retried
+	if n.retryJob(job, attempts, err) {
+		n.ChangeState("Searching")
+		return
 	}
 
+	// no retry left, so we move the job with its
+	// input to the dead letter queue for inspection
+	job.DeadLetter(err.Error(), attempts)
+
 	n.ChangeState("Searching")
 }
 
-func (n *Neuron) deleteJobAndInput(jobID int) {
-	jobQry := query.New().Read("Job").Match("ID", "==", strconv.Itoa(jobID)).To(
-		query.New().Read("Input"),
-	)
-	dat := n.memory.Gits.Query().Execute(jobQry)
+// retryJob reopens a failed job if the retry policy of its action allows
+// another attempt. The job gets a NotBefore timestamp based on the backoff
+// so it won't be picked up again before
+func (n *Neuron) retryJob(job *Job, attempts int, err error) bool {
+	jobAction, actionErr := n.cortex.GetAction(job.data.Properties["Action"])
+	if nil != actionErr {
+		return false
+	}
+	policy := jobAction.GetRetryPolicy()
+	if !policy.ShouldRetry(attempts, err) {
+		return false
+	}
 
-	unlinkQuery := query.New().Unlink("Job").Match("Value", "==", strconv.Itoa(dat.Entities[0].ID)).To(
-		query.New().Find("Input").Match("ID", "==", strconv.Itoa(dat.Entities[0].Children()[0].ID)),
-	)
-	n.memory.Gits.Query().Execute(unlinkQuery)
+	delay := policy.Delay(attempts)
+	job.SetProperty("Attempt", strconv.Itoa(attempts))
+	job.SetProperty("NotBefore", util.Timestamp(time.Now().Add(delay)))
+	job.SetProperty("LastError", err.Error())
+	job.SetState("Open")
+	n.log.Info("Job: "+job.data.Value+" retry scheduled", attempts, delay.String())
+	return true
+}
+
+func (n *Neuron) deleteJobAndInput(jobID int) {
+	job := Load(jobID, n.memory, n.log)
+	if nil != job {
+		job.Delete()
+	}
+}
 
-	jobDeleteQry := query.New().Delete("Job").Match("ID", "==", strconv.Itoa(dat.Entities[0].ID))
-	n.memory.Gits.Query().Execute(jobDeleteQry)
+// finishJobWithHistory keeps the job itself in state Done but
+// removes its input
+func (n *Neuron) finishJobWithHistory(jobID int) {
+	job := Load(jobID, n.memory, n.log)
+	if nil != job {
+		job.DeleteInput()
+		job.SetState("Done")
+	}
+}
 
-	inputDeleteQuery := query.New().Delete("Input").Match("ID", "==", strconv.Itoa(dat.Entities[0].Children()[0].ID))
-	n.memory.Gits.Query().Execute(inputDeleteQuery)
+// SetPriorityAging sets the interval after which a waiting job gains one
+// priority point, 0 disables aging
+func (n *Neuron) SetPriorityAging(aging time.Duration) {
+	n.aging = aging
+}
 
+// SetCategoryLimits sets the max amount of concurrent jobs per category
+func (n *Neuron) SetCategoryLimits(limits map[string]int) {
+	n.categoryLimits = limits
 }
 
-func (n *Neuron) deleteJobInput(jobID int) {
-	inputQry := query.New().Read("Input").From(
-		query.New().Read("Job").Match("ID", "==", strconv.Itoa(jobID)),
-	)
-	dat := n.memory.Gits.Query().Execute(inputQry)
+// getLimits returns the concurrency limits for a job by its properties
+func (n *Neuron) getLimits(properties map[string]string) ConcurrencyLimits {
+	limits := ConcurrencyLimits{
+		Category: n.categoryLimits[properties["Category"]],
+	}
+	if jobAction, err := n.cortex.GetAction(properties["Action"]); nil == err {
+		limits.Action = jobAction.GetConcurrency()
+	}
+	return limits
+}
 
-	inputDeleteQry := query.New().Delete("Input").Match("ID", "==", strconv.Itoa(dat.Entities[0].ID))
-	n.memory.Gits.Query().Execute(inputDeleteQry)
+// getRateLimit returns the rate limit of a job by its properties
+// and whether the job is rate limited at all
+func (n *Neuron) getRateLimit(properties map[string]string) (RateLimit, bool) {
+	if "" == properties["RateKey"] || nil == n.activity || nil == n.activity.RateLimiter {
+		return RateLimit{}, false
+	}
+	jobAction, err := n.cortex.GetAction(properties["Action"])
+	if nil != err {
+		return RateLimit{}, false
+	}
+	key, rateLimit := jobAction.GetRateLimit()
+	return rateLimit, "" != key
 }
 
 func (n *Neuron) EnableHistory() {
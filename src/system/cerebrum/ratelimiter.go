@@ -0,0 +1,74 @@
+package cerebrum
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimit allows Limit job starts within a sliding window of Per
+type RateLimit struct {
+	Limit int
+	Per   time.Duration
+}
+
+// RateLimiter keeps the start times of jobs per rate key. The key is
+// formed by an input entity, e.g. "IP:10.0.0.1", so all actions
+// working on the same target share one bucket
+type RateLimiter struct {
+	mutex   sync.Mutex
+	buckets map[string][]time.Time
+	// largest window seen, entries older than it are dropped
+	window time.Duration
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string][]time.Time),
+	}
+}
+
+// Allow returns true if the bucket of key has room left under the given limit
+func (r *RateLimiter) Allow(key string, limit RateLimit, now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.count(key, limit, now) < limit.Limit
+}
+
+// Take records a job start in the bucket of key if it has room left
+func (r *RateLimiter) Take(key string, limit RateLimit, now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.count(key, limit, now) >= limit.Limit {
+		return false
+	}
+	r.buckets[key] = append(r.buckets[key], now)
+	return true
+}
+
+// count returns the amount of job starts of key within the window of the
+// given limit. Caller has to hold the mutex
+func (r *RateLimiter) count(key string, limit RateLimit, now time.Time) int {
+	if limit.Per > r.window {
+		r.window = limit.Per
+	}
+	// drop everything no limit cares about anymore
+	bucket := r.buckets[key]
+	keep := 0
+	for keep < len(bucket) && !bucket[keep].After(now.Add(-r.window)) {
+		keep++
+	}
+	bucket = bucket[keep:]
+	if 0 == len(bucket) {
+		delete(r.buckets, key)
+		return 0
+	}
+	r.buckets[key] = bucket
+
+	amount := 0
+	for _, started := range bucket {
+		if started.After(now.Add(-limit.Per)) {
+			amount++
+		}
+	}
+	return amount
+}
@@ -3,10 +3,14 @@ package cerebrum
 import (
 	"crypto/sha1"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/voodooEntity/gits/src/query"
 	"github.com/voodooEntity/gits/src/transport"
@@ -25,6 +29,10 @@ type Scheduler struct {
 	patternMisses int
 	// track if we already printed a compile summary per key
 	patternSummarized map[string]bool
+	// guards the pattern cache and its diagnostics, the timer schedules
+	// periodic actions concurrently to the neurons. A pointer since the
+	// scheduler gets copied by value
+	patternMutex *sync.Mutex
 }
 
 func NewScheduler(memory *Memory, demultiplexerInstance *Demultiplexer, logger *archivist.Archivist) *Scheduler {
@@ -34,10 +42,32 @@ func NewScheduler(memory *Memory, demultiplexerInstance *Demultiplexer, logger *
 		log:               logger,
 		patternCache:      make(map[string]*PatternNode),
 		patternSummarized: make(map[string]bool),
+		patternMutex:      &sync.Mutex{},
 	}
 }
 
 func (s *Scheduler) Run(data transport.TransportEntity, cortex *Cortex) {
+	s.RunWithPriority(data, cortex, "")
+}
+
+// RunWithPriority schedules like Run but creates the jobs with the given
+// priority instead of the one of their action. An empty priority keeps
+// the action priority
+func (s *Scheduler) RunWithPriority(data transport.TransportEntity, cortex *Cortex, priority string) {
+	s.run(data, cortex, priority, nil)
+}
+
+// RunWithTrace schedules like Run and returns the trace of the decisions taken
+// for the batch, from the candidates found through the lookup nodes up to the
+// created jobs
+func (s *Scheduler) RunWithTrace(data transport.TransportEntity, cortex *Cortex) *Trace {
+	trace := NewTrace(data)
+	s.run(data, cortex, "", trace)
+	return trace
+}
+
+// run schedules the batch and records the decisions in the trace, if given
+func (s *Scheduler) run(data transport.TransportEntity, cortex *Cortex, priority string, trace *Trace) {
 	// scheduling: acknowledge that returned job output may be a subgraph; enrichment can extend upwards
 	s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED RUN begin root=", data.Type, ":", data.ID)
 	// We first identify potentially relevant actions/dependencies for this input batch.
@@ -76,6 +106,9 @@ func (s *Scheduler) Run(data transport.TransportEntity, cortex *Cortex) {
 		for _, ad := range actionsAndDependencies {
 			cand = append(cand, ad[0]+":"+ad[1])
 		}
+		if nil != trace {
+			trace.Candidates = cand
+		}
 		s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED candidates=", cand)
 	} else {
 		s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED candidates=[]")
@@ -105,7 +138,12 @@ func (s *Scheduler) Run(data transport.TransportEntity, cortex *Cortex) {
 	if len(anchors) == 0 {
 		anchors = append(anchors, data)
 	}
-	s.overlayProcessAnchors(anchors, actionsAndDependencies, data, newRelationStructures, cortex)
+	if nil != trace {
+		for _, anchor := range anchors {
+			trace.Anchors = append(trace.Anchors, traceLabel(anchor))
+		}
+	}
+	s.overlayProcessAnchors(anchors, actionsAndDependencies, data, newRelationStructures, cortex, priority, trace)
 }
 
 // findNodeByValue searches a dependency tree for a node whose Value matches the given type name.
@@ -113,8 +151,8 @@ func (s *Scheduler) findNodeByValue(root transport.TransportEntity, typeName str
 	if root.Value == typeName {
 		return &root
 	}
-	for _, ch := range root.Children() {
-		if hit := s.findNodeByValue(ch, typeName); hit != nil {
+	for _, related := range append(root.Children(), root.Parents()...) {
+		if hit := s.findNodeByValue(related, typeName); hit != nil {
 			return hit
 		}
 	}
@@ -143,10 +181,13 @@ func (s *Scheduler) extractBatchAnchors(entity transport.TransportEntity, relati
 	}
 	walk(entity)
 
-	// add child endpoints from relation-only structures
+	// add child endpoints from relation-only structures, typed relations are
+	// stored under two structure strings so endpoints are only added once
+	added := map[*transport.TransportEntity]bool{}
 	for _, pair := range relationStructures {
 		// pair[1] is the child target in rFilterRelationStructures
-		if pair[1] != nil {
+		if pair[1] != nil && !added[pair[1]] {
+			added[pair[1]] = true
 			anchors = append(anchors, *pair[1])
 		}
 	}
@@ -156,8 +197,9 @@ func (s *Scheduler) extractBatchAnchors(entity transport.TransportEntity, relati
 // overlayProcessAnchors implements a minimal anchor-driven overlay:
 // for each anchor, it restricts candidates to actions whose pattern contains the
 // anchor type, builds lookup/pointer from the anchor subgraph, constructs inputs,
-// then enforces causality and idempotency before creating jobs.
-func (s *Scheduler) overlayProcessAnchors(anchors []transport.TransportEntity, actionsAndDependencies [][2]string, batch transport.TransportEntity, newRelationStructures map[string][2]*transport.TransportEntity, cortex *Cortex) {
+// then enforces causality and idempotency before creating jobs. Decisions are
+// recorded in the trace, if given.
+func (s *Scheduler) overlayProcessAnchors(anchors []transport.TransportEntity, actionsAndDependencies [][2]string, batch transport.TransportEntity, newRelationStructures map[string][2]*transport.TransportEntity, cortex *Cortex, priority string, trace *Trace) {
 	// Pre-compute updated entity IDs from the full batch to enforce strict causality.
 	updatedIDs := s.collectUpdatedEntityIDs(batch, newRelationStructures)
 	// Collect bMap updated keys at batch root (common case: single-entity updates)
@@ -177,60 +219,294 @@ func (s *Scheduler) overlayProcessAnchors(anchors []transport.TransportEntity, a
 		for _, ad := range actionsAndDependencies {
 			act, _ := cortex.GetAction(ad[0])
 			requirement := act.GetDependencyByName(ad[1])
-			// Ensure the compiled pattern for this dependency contains the anchor type.
-			if !s.patternContainsType(act.GetName(), requirement, anchor.Type) {
+			ts := trace.scope(act.GetName(), ad[1], anchor)
+			// Ensure the compiled pattern for this dependency contains the anchor type,
+			// absent nodes of its type only lead to the revocation of open jobs.
+			contained := s.patternContainsType(act.GetName(), requirement, anchor.Type)
+			absent := s.patternContainsAbsentType(act.GetName(), requirement, anchor.Type)
+			if !contained && !absent {
+				ts.record(TRACE_STEP_PATTERN, false, nil, "type="+anchor.Type)
 				continue
 			}
-			// If this batch carries property updates (bMap with keys) and the dependency is MATCH-mode
-			// on fields that are not among updated keys, skip due to irrelevance.
+			// If this batch carries property updates (bMap with keys) that neither match a filter
+			// field nor make the input count as new for the idempotency mode, skip due to irrelevance.
 			if batchBMapValue != "" {
-				if !s.hasRelevantFilter(requirement, batchBMapValue) {
+				if !s.isRelevantUpdate(act, requirement, batchBMapValue) {
 					s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED RELEVANCE matchedKey=none (skip)")
+					ts.record(TRACE_STEP_RELEVANCE, false, nil, "updated="+batchBMapValue)
 					continue
 				}
 			}
+			// A node the dependency requires to be absent appeared, re-evaluate open jobs.
+			// Tracing only records decisions, so jobs are left untouched
+			if absent && nil == trace {
+				s.revokeAbsentJobs(act, ad[1], requirement, anchor)
+			}
+			if !contained {
+				ts.record(TRACE_STEP_PATTERN, false, nil, "type="+anchor.Type)
+				continue
+			}
 			// Build candidate inputs using existing query builder, constrained by lookup.
-			inputs := s.buildInputData(requirement.Children()[0], lookup, pointer)
+			inputs := s.buildInputData(requirement.Children()[0], lookup, pointer, ts)
+			ts.record(TRACE_STEP_QUERY, 0 < len(inputs), nil, "inputs="+strconv.Itoa(len(inputs)))
 			for _, input := range inputs {
 				// Ensure the constructed input contains the anchor entity (Type,ID).
 				if !s.inputContainsEntity(&input, anchor.Type, anchor.ID) {
+					ts.record(TRACE_STEP_ANCHOR, false, &input, "")
 					continue
 				}
 				// Enforce strict causality: input must include an updated entity from this batch.
 				if !s.inputContainsUpdated(&input, updatedIDs) {
 					s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED CAUSALITY action=", act.GetName(), " dep=", ad[1], " containsUpdated=", false)
+					ts.record(TRACE_STEP_CAUSALITY, false, &input, "")
 					continue
 				}
 				s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED CAUSALITY action=", act.GetName(), " dep=", ad[1], " containsUpdated=", true)
+				ts.record(TRACE_STEP_CAUSALITY, true, &input, "")
 				sig := util.GenerateSignature(input)
 				// Witness / Memory idempotency guard
-				if s.isDuplicateByWitness(act.GetName(), ad[1], input, requirement) {
+				duplicate, witness := s.isDuplicateByWitness(act, ad[1], input, requirement)
+				ts.record(TRACE_STEP_WITNESS, !duplicate, &input, "sig="+witness)
+				if duplicate {
 					s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED JOB skip duplicate by Memory witness action=", act.GetName(), " dep=", ad[1])
 					continue
 				}
 				s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED JOB create action=", act.GetName(), " dep=", ad[1], " sig=", sig)
-				newJob := NewJob(s.memory, s.log)
-				created := newJob.Create(act.GetName(), ad[1], input)
+				created := s.prepareJob(act, requirement, input, witness).SetSeedPriority(priority).Create(act.GetName(), ad[1], input)
 				s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED JOB persisted id=", created.id, " action=", act.GetName(), " dep=", ad[1])
+				ts.job(created.id)
 			}
 		}
 	}
 }
 
+// Explain dry runs the dependency depName of the action against the stored
+// entity entityType:entityID as if it had just been learned, and returns the
+// decisions the scheduler would take. Neither witnesses nor jobs are created,
+// job decisions only state that a job would be created
+func (s *Scheduler) Explain(cortex *Cortex, actionName string, depName string, entityType string, entityID int) (*Trace, error) {
+	act, err := cortex.GetAction(actionName)
+	if nil != err {
+		return nil, err
+	}
+	requirement := act.GetDependencyByName(depName)
+	if "" == requirement.Value || 0 == len(requirement.Children()) {
+		return nil, errors.New("dependency '" + depName + "' not found on action '" + actionName + "'")
+	}
+	result := s.memory.Gits.Query().Execute(query.New().Read(entityType).Match("ID", "==", strconv.Itoa(entityID)))
+	if 0 == len(result.Entities) {
+		return nil, errors.New("entity " + entityType + ":" + strconv.Itoa(entityID) + " not found")
+	}
+	entity := result.Entities[0]
+	trace := NewTrace(entity)
+	trace.Anchors = append(trace.Anchors, traceLabel(entity))
+	for _, ad := range s.retrieveActionsByType(entityType) {
+		trace.Candidates = append(trace.Candidates, ad[0]+":"+ad[1])
+	}
+	ts := trace.scope(actionName, depName, entity)
+	if !util.StringInArray(trace.Candidates, actionName+":"+depName) {
+		ts.record(TRACE_STEP_LOOKUP, false, nil, "type="+entityType)
+		return trace, nil
+	}
+	if !s.patternContainsType(actionName, requirement, entityType) {
+		ts.record(TRACE_STEP_PATTERN, false, nil, "type="+entityType)
+		return trace, nil
+	}
+	lookup := make(map[string]int)
+	var pointer [][]*transport.TransportEntity
+	lookup, pointer = s.rEnrichLookupAndPointer(entity, lookup, pointer)
+	inputs := s.buildInputData(requirement.Children()[0], lookup, pointer, ts)
+	ts.record(TRACE_STEP_QUERY, 0 < len(inputs), nil, "inputs="+strconv.Itoa(len(inputs)))
+	for _, input := range inputs {
+		if !s.inputContainsEntity(&input, entity.Type, entity.ID) {
+			ts.record(TRACE_STEP_ANCHOR, false, &input, "")
+			continue
+		}
+		_, witness := s.witnessSignature(act, depName, input, requirement, "")
+		duplicate := s.hasWitness(witness, time.Now())
+		ts.record(TRACE_STEP_WITNESS, !duplicate, &input, "sig="+witness)
+		if duplicate {
+			continue
+		}
+		ts.record(TRACE_STEP_JOB, true, &input, "dry run")
+	}
+	return trace, nil
+}
+
+// RunPeriodic re-runs a periodic action against every input currently matching
+// its dependencies. bucket identifies the interval the run belongs to and is part
+// of the witness, so each input gets one job per bucket. Inputs are loaded and
+// scheduled batchSize root entities at a time. Returns the amount of jobs created
+func (s *Scheduler) RunPeriodic(act *Action, bucket string, batchSize int) int {
+	created := 0
+	for _, requirement := range act.GetDependencies() {
+		if 0 == len(requirement.Children()) {
+			continue
+		}
+		created += s.scheduleRoots(act, requirement, batchSize, bucket)
+	}
+	return created
+}
+
+// Backfill schedules an action against the data already known before it has
+// been registered. It walks all inputs matching the dependency depName, or all
+// dependencies if depName is empty, and applies the regular witness guard so
+// inputs the action already ran on are skipped. Inputs are loaded and scheduled
+// batchSize root entities at a time. Returns the amount of jobs created
+func (s *Scheduler) Backfill(act *Action, depName string, batchSize int) (int, error) {
+	if 0 >= batchSize {
+		return 0, errors.New("backfill batch size has to be greater than 0")
+	}
+	created := 0
+	found := false
+	for _, requirement := range act.GetDependencies() {
+		if "" != depName && requirement.Value != depName {
+			continue
+		}
+		found = true
+		if 0 == len(requirement.Children()) {
+			continue
+		}
+		created += s.scheduleRoots(act, requirement, batchSize, "")
+	}
+	if !found {
+		return 0, errors.New("dependency '" + depName + "' not found on action '" + act.GetName() + "'")
+	}
+	return created, nil
+}
+
+// scheduleRoots schedules the action against all inputs of the requirement.
+// It pages over the root IDs so only batchSize roots with their structure
+// are held at a time. Returns the amount of jobs created
+func (s *Scheduler) scheduleRoots(act *Action, requirement transport.TransportEntity, batchSize int, bucket string) int {
+	created := 0
+	root := requirement.Children()[0]
+	for ids := s.nextRootIDs(root, 0, batchSize); 0 < len(ids); ids = s.nextRootIDs(root, ids[len(ids)-1], batchSize) {
+		batch := make([]string, 0, len(ids))
+		for _, id := range ids {
+			batch = append(batch, strconv.Itoa(id))
+		}
+		qry := s.rBuildQuery(root, map[string]int{}, nil).Match("ID", "in", strings.Join(batch, ","))
+		inputs := s.parseInputs(root, s.memory.Gits.Query().Execute(qry).Entities, nil)
+		amount := s.scheduleInputs(act, requirement, inputs, bucket)
+		s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED ROOTS batch action=", act.GetName(), " dep=", requirement.Value, " bucket=", bucket, " roots=", len(ids), " jobs=", amount)
+		created += amount
+	}
+	return created
+}
+
+// nextRootIDs returns up to limit IDs greater than after, in ascending order,
+// of the entities that could be the root of a dependency. The storage is
+// walked holding no more than two batches of IDs and without copying any
+// entity. The root filters are applied like rBuildQuery does, the rest of the
+// structure is resolved per batch. Entities in the scopes of the cyberbrain
+// itself (jobs, neurons, witnesses, ...) are never roots
+func (s *Scheduler) nextRootIDs(root transport.TransportEntity, after int, limit int) []int {
+	var filters [][3]string
+	if root.Properties["Mode"] == "Match" {
+		filters = s.prefixedFilters(root, "Filter")
+	}
+	internal := []string{s.memory.Scope("System"), s.memory.Scope("Cyberbrain")}
+
+	storage := s.memory.Gits.Storage()
+	storage.EntityStorageMutex.Lock()
+	defer storage.EntityStorageMutex.Unlock()
+	typeID, err := storage.GetTypeIdByStringUnsafe(root.Value)
+	if nil != err {
+		return nil
+	}
+	ids := make([]int, 0, 2*limit)
+	for id, entity := range storage.EntityStorage[typeID] {
+		if id <= after || util.StringInArray(internal, entity.Context) {
+			continue
+		}
+		candidate := transport.TransportEntity{ID: id, Type: root.Value, Value: entity.Value, Context: entity.Context, Properties: entity.Properties}
+		matches := true
+		for _, filter := range filters {
+			if !util.MatchValue(util.ResolveEntityField(candidate, filter[0]), filter[1], filter[2]) {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		ids = append(ids, id)
+		// keep only the lowest IDs so we never hold more than two batches
+		if len(ids) == 2*limit {
+			sort.Ints(ids)
+			ids = ids[:limit]
+		}
+	}
+	sort.Ints(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids
+}
+
+// scheduleInputs creates a job for every input that isn't a duplicate by
+// witness. Returns the amount of jobs created
+func (s *Scheduler) scheduleInputs(act *Action, requirement transport.TransportEntity, inputs []transport.TransportEntity, bucket string) int {
+	created := 0
+	for _, input := range inputs {
+		duplicate, witness := s.isDuplicateByWitnessInBucket(act, requirement.Value, input, requirement, bucket)
+		if duplicate {
+			s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED skip duplicate action=", act.GetName(), " dep=", requirement.Value, " bucket=", bucket)
+			continue
+		}
+		job := s.prepareJob(act, requirement, input, witness).Create(act.GetName(), requirement.Value, input)
+		s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED JOB persisted id=", job.id, " action=", act.GetName(), " dep=", requirement.Value, " bucket=", bucket)
+		created++
+	}
+	return created
+}
+
+// prepareJob sets up a new job with everything derived from its action
+func (s *Scheduler) prepareJob(act *Action, requirement transport.TransportEntity, input transport.TransportEntity, witness string) *Job {
+	job := NewJob(s.memory, s.log).SetWitness(witness).SetPriority(act.GetPriority()).SetCategory(act.GetCategory()).SetRateKey(s.buildRateKey(act, requirement, input))
+	if delay := act.GetDelay(); 0 < delay {
+		job.SetNotBefore(time.Now().Add(delay))
+	}
+	return job
+}
+
 // patternContainsType returns true if the compiled pattern for the dependency contains
-// a node with the given type.
+// a node with the given type. Absent nodes and their children don't count.
 func (s *Scheduler) patternContainsType(actionName string, dep transport.TransportEntity, typeName string) bool {
+	pn := s.getOrCompilePattern(actionName, dep)
+	var has func(n *PatternNode) bool
+	has = func(n *PatternNode) bool {
+		if n == nil || n.Mode == "Absent" {
+			return false
+		}
+		if n.Type == typeName || util.StringInArray(n.Via, typeName) {
+			return true
+		}
+		for _, related := range append(n.Children, n.Parents...) {
+			if has(related) {
+				return true
+			}
+		}
+		return false
+	}
+	return has(pn)
+}
+
+// patternContainsAbsentType checks if the compiled pattern has an absent node
+// of the given type
+func (s *Scheduler) patternContainsAbsentType(actionName string, dep transport.TransportEntity, typeName string) bool {
 	pn := s.getOrCompilePattern(actionName, dep)
 	var has func(n *PatternNode) bool
 	has = func(n *PatternNode) bool {
 		if n == nil {
 			return false
 		}
-		if n.Type == typeName {
+		if n.Mode == "Absent" && n.Type == typeName {
 			return true
 		}
-		for _, ch := range n.Children {
-			if has(ch) {
+		for _, related := range append(n.Children, n.Parents...) {
+			if has(related) {
 				return true
 			}
 		}
@@ -246,6 +522,14 @@ func (s *Scheduler) inputContainsEntity(input *transport.TransportEntity, t stri
 		if !found && e.Type == t && e.ID == id {
 			found = true
 		}
+		// entities traversed by a path are part of the input as well
+		for _, relation := range append(e.ChildRelations, e.ParentRelations...) {
+			for _, hop := range s.pathHops(relation) {
+				if !found && hop.Type == t && hop.ID == id {
+					found = true
+				}
+			}
+		}
 	})
 	return found
 }
@@ -254,23 +538,36 @@ func (s *Scheduler) rFilterRelationStructures(entity transport.TransportEntity,
 	if 0 < len(entity.ChildRelations) {
 		for _, childRelation := range entity.ChildRelations {
 			if _, ok := childRelation.Properties["bMap"]; ok && childRelation.Properties["bMap"] == "" { // #
-				tmpRelString := entity.Type + "-" + childRelation.Target.Type
-				add := true
-				for knownRelString, _ := range relationStructures {
-					if knownRelString == tmpRelString {
-						add = false
-					}
-				}
-				if add {
-					relationStructures[tmpRelString] = [2]*transport.TransportEntity{&entity, &childRelation.Target}
-				}
+				s.addRelationStructure(relationStructures, childRelation.Context, [2]*transport.TransportEntity{&entity, &childRelation.Target})
 			}
 			relationStructures = s.rFilterRelationStructures(childRelation.Target, relationStructures)
 		}
 	}
+	// new parent relations are stored as parent-entity with the entity as child endpoint
+	for key, parentRelation := range entity.ParentRelations {
+		if _, ok := parentRelation.Properties["bMap"]; ok && parentRelation.Properties["bMap"] == "" {
+			s.addRelationStructure(relationStructures, parentRelation.Context, [2]*transport.TransportEntity{&entity.ParentRelations[key].Target, &entity})
+		}
+		relationStructures = s.rFilterRelationStructures(parentRelation.Target, relationStructures)
+	}
 	return relationStructures
 }
 
+// addRelationStructure stores the parent/child pair of a new relation by its
+// relation structure string. Typed relations are stored a second time including
+// their context, so patterns with and without relation context find them
+func (s *Scheduler) addRelationStructure(relationStructures map[string][2]*transport.TransportEntity, context string, pair [2]*transport.TransportEntity) {
+	keys := []string{relationStructureString(pair[0].Type, "", pair[1].Type)}
+	if "" != context {
+		keys = append(keys, relationStructureString(pair[0].Type, context, pair[1].Type))
+	}
+	for _, key := range keys {
+		if _, known := relationStructures[key]; !known {
+			relationStructures[key] = pair
+		}
+	}
+}
+
 func (s *Scheduler) createNewJobs(entity transport.TransportEntity, newRelationStructures map[string][2]*transport.TransportEntity, cortex *Cortex) []transport.TransportEntity {
 	// first we will enrich some lookup variables we need later on
 	// by recursively walking the given data
@@ -323,7 +620,7 @@ func (s *Scheduler) createNewJobs(entity transport.TransportEntity, newRelationS
 		}
 
 		s.log.DebugF(archivist.DEBUG_LEVEL_DUMP, "Trying to enrich data based on %+v ", actionAndDependency)
-		newJobInputs := s.buildInputData(requirement.Children()[0], lookup, pointer)
+		newJobInputs := s.buildInputData(requirement.Children()[0], lookup, pointer, nil)
 		//inputData, err := rBuildInputData(requirement.Children()[0], entity, pointer, lookup, false, "", -1, nil)
 		if 0 < len(newJobInputs) {
 			for _, inputData := range newJobInputs {
@@ -338,13 +635,13 @@ func (s *Scheduler) createNewJobs(entity transport.TransportEntity, newRelationS
 				s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED CAUSALITY action=", act.GetName(), " dep=", actionAndDependency[1], " containsUpdated=", true)
 				s.log.DebugF(archivist.DEBUG_LEVEL_DUMP, "Created a new job with payload %+v", inputData)
 				// Witness / Memory idempotency guard (anchor-sharded, no global index)
-				if s.isDuplicateByWitness(act.GetName(), actionAndDependency[1], inputData, requirement) {
+				duplicate, witness := s.isDuplicateByWitness(act, actionAndDependency[1], inputData, requirement)
+				if duplicate {
 					s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED JOB skip duplicate by Memory witness action=", act.GetName(), " dep=", actionAndDependency[1])
 					continue
 				}
 				s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED JOB create action=", act.GetName(), " dep=", actionAndDependency[1], " sig=", sig)
-				newJob := NewJob(s.memory, s.log)
-				created := newJob.Create(act.GetName(), actionAndDependency[1], inputData)
+				created := s.prepareJob(act, requirement, inputData, witness).Create(act.GetName(), actionAndDependency[1], inputData)
 				s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED JOB persisted id=", created.id, " action=", act.GetName(), " dep=", actionAndDependency[1])
 			}
 		} else {
@@ -358,14 +655,19 @@ func (s *Scheduler) createNewJobs(entity transport.TransportEntity, newRelationS
 // isDuplicateByWitness implements a local, anchor-sharded idempotency check using a Memory entity.
 // It does NOT use any global index. The Memory node is created (if missing) with Context "Exec:<Action>:<Dep>"
 // and Value=<signatureHash>. We link Anchor -> Memory for locality. If Memory already exists, we skip scheduling.
-func (s *Scheduler) isDuplicateByWitness(actionName, depName string, input transport.TransportEntity, requirement transport.TransportEntity) bool {
-	// Determine a deterministic anchor for this input
-	anchor := s.selectAnchorForInput(input, requirement)
-	// Build canonical signature string and hash it to keep Value compact
-	sigStr := s.buildWitnessSignatureString(actionName, depName, anchor, input)
-	sigHash := sha1.Sum([]byte(sigStr))
-	sigHex := hex.EncodeToString(sigHash[:])
-	ctx := fmt.Sprintf("Exec:%s:%s", actionName, depName)
+// The signature hash is returned alongside so it can be stored on the created job.
+// Witnesses carry their Created time and, if the action has a witness TTL, an Expires
+// time after which the input may be scheduled again.
+func (s *Scheduler) isDuplicateByWitness(act *Action, depName string, input transport.TransportEntity, requirement transport.TransportEntity) (bool, string) {
+	return s.isDuplicateByWitnessInBucket(act, depName, input, requirement, "")
+}
+
+// isDuplicateByWitnessInBucket works like isDuplicateByWitness but adds a time bucket
+// to the signature, so periodic runs are only deduplicated within the same bucket.
+func (s *Scheduler) isDuplicateByWitnessInBucket(act *Action, depName string, input transport.TransportEntity, requirement transport.TransportEntity, bucket string) (bool, string) {
+	anchor, sigHex := s.witnessSignature(act, depName, input, requirement, bucket)
+	ctx := fmt.Sprintf("Exec:%s:%s", act.GetName(), depName)
+	ttl := s.witnessTTL(act, bucket)
 
 	// Try to map (or match) Memory by Value using ID=-2 semantics
 	memNode := s.memory.Mapper.MapTransportDataWithContext(transport.TransportEntity{
@@ -374,17 +676,64 @@ func (s *Scheduler) isDuplicateByWitness(actionName, depName string, input trans
 		Value:      sigHex,
 		Context:    ctx,
 		Properties: map[string]string{},
-	}, "System")
+	}, s.memory.Scope("System"))
 
 	// If newly created, Mapper sets Properties["bMap"] = "" — then we must link Anchor->Memory and allow job creation
 	if _, created := memNode.Properties["bMap"]; created {
 		// Link anchor -> memory (best-effort; relationExists guard in storage avoids duplicates)
 		s.linkAnchorToMemory(anchor, memNode)
+		s.stampWitness(memNode.ID, ttl, time.Now())
 		s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED WITNESS created ctx=", ctx, " val=", sigHex)
-		return false
+		return false, sigHex
+	}
+	// Expired witness → renew it and allow rescheduling, unless the last job is still active
+	if !HasActiveJobWithWitness(s.memory, sigHex, 0) && s.renewExpiredWitness(memNode.ID, ttl, time.Now()) {
+		s.linkAnchorToMemory(anchor, memNode)
+		s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED WITNESS expired ctx=", ctx, " val=", sigHex)
+		return false, sigHex
 	}
 	// Existing witness → duplicate
 	s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED WITNESS exists ctx=", ctx, " val=", sigHex)
+	return true, sigHex
+}
+
+// witnessTTL returns how long a witness guards its input. Witnesses of a
+// periodic run are only needed for their bucket, so they expire after at
+// least one interval instead of piling up bucket after bucket
+func (s *Scheduler) witnessTTL(act *Action, bucket string) time.Duration {
+	ttl := act.GetWitnessTTL()
+	if "" != bucket && ttl < act.GetInterval() {
+		ttl = act.GetInterval()
+	}
+	return ttl
+}
+
+// witnessSignature returns the anchor of the input and the hashed signature its
+// witness is stored under
+func (s *Scheduler) witnessSignature(act *Action, depName string, input transport.TransportEntity, requirement transport.TransportEntity, bucket string) (transport.TransportEntity, string) {
+	// Determine a deterministic anchor for this input
+	anchor := s.selectAnchorForInput(input, requirement)
+	// Build canonical signature string and hash it to keep Value compact
+	sigStr := s.buildWitnessSignatureString(act, depName, anchor, input, requirement)
+	if "" != bucket {
+		sigStr += "|@" + bucket
+	}
+	sigHash := sha1.Sum([]byte(sigStr))
+	return anchor, hex.EncodeToString(sigHash[:])
+}
+
+// hasWitness checks without creating or renewing anything if a witness blocks
+// the input from being scheduled again. Expired witnesses only block while the
+// last job is still active
+func (s *Scheduler) hasWitness(sigHex string, now time.Time) bool {
+	ret := s.memory.Gits.Query().Execute(query.New().Read("Memory").Match("Value", "==", sigHex).Match("Context", "==", s.memory.Scope("System")))
+	if 0 == len(ret.Entities) {
+		return false
+	}
+	expires, ok := util.ParseTimestamp(ret.Entities[0].Properties["Expires"])
+	if ok && !now.Before(expires) && !HasActiveJobWithWitness(s.memory, sigHex, 0) {
+		return false
+	}
 	return true
 }
 
@@ -398,8 +747,8 @@ func (s *Scheduler) selectAnchorForInput(input transport.TransportEntity, requir
 		if n.Type == "Structure" && n.Properties["Type"] == "Primary" {
 			primaryTypes = append(primaryTypes, n.Value)
 		}
-		for _, ch := range n.Children() {
-			walkReq(ch)
+		for _, related := range append(n.Children(), n.Parents()...) {
+			walkReq(related)
 		}
 	}
 	if requirement.Type == "Dependency" && len(requirement.ChildRelations) > 0 {
@@ -445,6 +794,71 @@ func (s *Scheduler) findFirstInInputByType(root *transport.TransportEntity, t st
 	return nil, false
 }
 
+// buildRateKey returns "Type:Value" of the input entity named by the rate limit
+// of the action. The name is resolved as dependency alias first, else as type
+func (s *Scheduler) buildRateKey(act *Action, requirement transport.TransportEntity, input transport.TransportEntity) string {
+	key, _ := act.GetRateLimit()
+	if "" == key {
+		return ""
+	}
+	target, ok := s.findInputByAliasOrType(requirement, input, key)
+	if !ok {
+		s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED RATEKEY not found in input action=", act.GetName(), " key=", key)
+		return ""
+	}
+	return target.Type + ":" + target.Value
+}
+
+// buildIdempotencyKey evaluates the idempotency key expressions ("<alias>.Value" or
+// "<alias>.Properties.<key>") of the action against the input. Returns false if the
+// action has no key or an expression can't be resolved.
+func (s *Scheduler) buildIdempotencyKey(act *Action, requirement transport.TransportEntity, input transport.TransportEntity) (string, bool) {
+	expressions := act.GetIdempotencyKey()
+	if 0 == len(expressions) {
+		return "", false
+	}
+	var sb strings.Builder
+	for _, expression := range expressions {
+		parts := strings.SplitN(expression, ".", 2)
+		if 2 != len(parts) {
+			s.log.Error("Invalid idempotency key expression", act.GetName(), expression)
+			return "", false
+		}
+		target, ok := s.findInputByAliasOrType(requirement, input, parts[0])
+		if !ok {
+			s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED IDEMPOTENCYKEY not found in input action=", act.GetName(), " expression=", expression)
+			return "", false
+		}
+		sb.WriteString(fmt.Sprintf("%s=%q;", expression, util.ResolveEntityField(*target, parts[1])))
+	}
+	return sb.String(), true
+}
+
+// findInputByAliasOrType returns the input entity for the given name, which is resolved
+// as dependency alias first, else as type.
+func (s *Scheduler) findInputByAliasOrType(requirement transport.TransportEntity, input transport.TransportEntity, name string) (*transport.TransportEntity, bool) {
+	typeName := name
+	if 0 < len(requirement.Children()) {
+		if node := s.findNodeByAlias(requirement.Children()[0], name); nil != node {
+			typeName = node.Value
+		}
+	}
+	return s.findFirstInInputByType(&input, typeName)
+}
+
+// findNodeByAlias searches a dependency tree for a structure node with the given alias.
+func (s *Scheduler) findNodeByAlias(root transport.TransportEntity, alias string) *transport.TransportEntity {
+	if root.Properties["Alias"] == alias {
+		return &root
+	}
+	for _, related := range append(root.Children(), root.Parents()...) {
+		if hit := s.findNodeByAlias(related, alias); hit != nil {
+			return hit
+		}
+	}
+	return nil
+}
+
 // rWalkInput walks the transport entity graph (children and parents) and invokes fn for each node.
 func (s *Scheduler) rWalkInput(e *transport.TransportEntity, fn func(*transport.TransportEntity)) {
 	if e == nil {
@@ -460,11 +874,98 @@ func (s *Scheduler) rWalkInput(e *transport.TransportEntity, fn func(*transport.
 }
 
 // buildWitnessSignatureString creates the canonical signature string used for Memory.Value.
-func (s *Scheduler) buildWitnessSignatureString(actionName, depName string, anchor transport.TransportEntity, input transport.TransportEntity) string {
-	base := actionName + "|" + depName + "|" + anchor.Type + ":" + strconv.Itoa(anchor.ID) + "|"
+// The memory ident is part of the signature since witnesses are matched by value across the
+// whole graph and must not collide between cyberbrains sharing it. The input part depends
+// on the idempotency mode of the action. The legacy default includes Versions, which the
+// demultiplexed inputs don't carry, so it only changes with the structure of the input.
+// An idempotency key declared on the action replaces anchor and input signature.
+func (s *Scheduler) buildWitnessSignatureString(act *Action, depName string, anchor transport.TransportEntity, input transport.TransportEntity, requirement transport.TransportEntity) string {
+	prefix := act.GetName() + "|" + depName + "|"
+	if "" != s.memory.Ident {
+		prefix = s.memory.Ident + "|" + prefix
+	}
+	if key, ok := s.buildIdempotencyKey(act, requirement, input); ok {
+		return prefix + "key|" + key
+	}
+	base := prefix + anchor.Type + ":" + strconv.Itoa(anchor.ID) + "|"
+	mode, fields := act.GetIdempotency()
+	switch mode {
+	case "Identity":
+		return base + util.GenerateIdentitySignature(input)
+	case "Fields":
+		return base + util.GenerateFieldSignature(input, fields)
+	case "Content":
+		return base + util.GenerateContentSignature(input)
+	case "":
+	default:
+		s.log.Error("Unknown idempotency mode, falling back to versions", act.GetName(), mode)
+	}
 	return base + util.GenerateSignature(input)
 }
 
+// stampWitness stores the creation time and the expiry, if a ttl is given, on a witness.
+func (s *Scheduler) stampWitness(id int, ttl time.Duration, now time.Time) {
+	q := query.New().Update("Memory").Match("ID", "==", strconv.Itoa(id)).Set("Properties.Created", util.Timestamp(now))
+	if 0 < ttl {
+		q.Set("Properties.Expires", util.Timestamp(now.Add(ttl)))
+	}
+	s.memory.Gits.Query().Execute(q)
+}
+
+// renewExpiredWitness restamps an expired witness and returns true, if no other
+// neuron renewed it in between. The check is done under the storage lock.
+func (s *Scheduler) renewExpiredWitness(id int, ttl time.Duration, now time.Time) bool {
+	storage := s.memory.Gits.Storage()
+	storage.EntityStorageMutex.Lock()
+	defer storage.EntityStorageMutex.Unlock()
+	memoryTypeID, err := storage.GetTypeIdByStringUnsafe("Memory")
+	if nil != err {
+		return false
+	}
+	witness, err := storage.GetEntityByPathUnsafe(memoryTypeID, id, "")
+	if nil != err {
+		return false
+	}
+	expires, ok := util.ParseTimestamp(witness.Properties["Expires"])
+	if !ok || now.Before(expires) {
+		return false
+	}
+	properties := make(map[string]string, len(witness.Properties))
+	for key, value := range witness.Properties {
+		properties[key] = value
+	}
+	properties["Created"] = util.Timestamp(now)
+	delete(properties, "Expires")
+	if 0 < ttl {
+		properties["Expires"] = util.Timestamp(now.Add(ttl))
+	}
+	witness.Properties = properties
+	return nil == storage.UpdateEntityUnsafe(witness)
+}
+
+// SweepWitnesses deletes all expired witnesses of this cyberbrain together with
+// their anchor relations. Returns the amount of witnesses deleted.
+func (s *Scheduler) SweepWitnesses(now time.Time) int {
+	ret := s.memory.Gits.Query().Execute(query.New().Read("Memory").Match("Context", "==", s.memory.Scope("System")))
+	deleted := 0
+	for _, witness := range ret.Entities {
+		expires, ok := util.ParseTimestamp(witness.Properties["Expires"])
+		if !ok || now.Before(expires) {
+			continue
+		}
+		// the witness still guards a job that has not finished yet
+		if HasActiveJobWithWitness(s.memory, witness.Value, 0) {
+			continue
+		}
+		// deleting the witness also removes its anchor relations
+		deleted += s.memory.Gits.Query().Execute(query.New().Delete("Memory").Match("ID", "==", strconv.Itoa(witness.ID))).Amount
+	}
+	if 0 < deleted {
+		s.log.Debug(archivist.DEBUG_LEVEL_INFO, "Swept expired witnesses", deleted)
+	}
+	return deleted
+}
+
 // linkAnchorToMemory creates a relation between the anchor and the Memory node using Gits queries.
 func (s *Scheduler) linkAnchorToMemory(anchor transport.TransportEntity, memoryNode transport.TransportEntity) {
 	// Build query: Link <anchor.Type>[ID==anchor.ID] -> Memory[ID==memoryNode.ID]
@@ -483,6 +984,8 @@ func (s *Scheduler) linkAnchorToMemory(anchor transport.TransportEntity, memoryN
 func (s *Scheduler) getOrCompilePattern(actionName string, dep transport.TransportEntity) *PatternNode {
 	// Key by action + dependency ID; IDs are stable within type scope.
 	key := actionName + "|" + strconv.Itoa(dep.ID)
+	s.patternMutex.Lock()
+	defer s.patternMutex.Unlock()
 	if pn, ok := s.patternCache[key]; ok {
 		s.patternHits++
 		s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling PATTERN cache hit key=", key, " hits=", s.patternHits, " misses=", s.patternMisses)
@@ -498,13 +1001,18 @@ func (s *Scheduler) getOrCompilePattern(actionName string, dep transport.Transpo
 		root = dep
 	}
 	compiled := s.compilePatternNode(root)
+	// references to aliases that don't exist can never match, the pattern is rejected
+	if err := s.validateReferences(compiled); nil != err {
+		s.log.Error("scheduling PATTERN rejected key=", key, " ", err)
+		compiled = nil
+	}
 	// validate duplicate aliases and log once per dependency key
-	if s.hasDuplicateAliases(compiled) {
+	if nil != compiled && s.hasDuplicateAliases(compiled) {
 		s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling PATTERN duplicate-alias warning key=", key)
 	}
 	s.patternCache[key] = compiled
 	// one-line summary once
-	if !s.patternSummarized[key] {
+	if nil != compiled && !s.patternSummarized[key] {
 		s.patternSummarized[key] = true
 		slots := s.collectSlotLabels(compiled)
 		matchCnt := s.countMatchNodes(compiled)
@@ -517,8 +1025,25 @@ func (s *Scheduler) getOrCompilePattern(actionName string, dep transport.Transpo
 func (s *Scheduler) compilePatternNode(n transport.TransportEntity) *PatternNode {
 	// Collect filters from Properties where keys are Filter.<name>.(Field|Operator|Value)
 	filters := map[string][3]string{}
+	references := map[string][3]string{}
 	normalized := map[string]string{}
 	for k, v := range n.Properties {
+		if strings.HasPrefix(k, "Reference.") {
+			parts := strings.Split(k, ".")
+			if len(parts) == 3 {
+				rec := references[parts[1]]
+				switch parts[2] {
+				case "Field":
+					rec[0] = v
+				case "Operator":
+					rec[1] = v
+				case "Value":
+					rec[2] = v
+				}
+				references[parts[1]] = rec
+			}
+			continue
+		}
 		if strings.HasPrefix(k, "Filter.") {
 			parts := strings.Split(k, ".")
 			if len(parts) == 3 {
@@ -573,13 +1098,31 @@ func (s *Scheduler) compilePatternNode(n transport.TransportEntity) *PatternNode
 	for _, cw := range children {
 		kids = append(kids, s.compilePatternNode(cw.e))
 	}
+	var parents []*PatternNode
+	for _, parent := range n.Parents() {
+		parents = append(parents, s.compilePatternNode(parent))
+	}
+	sort.SliceStable(parents, func(i, j int) bool {
+		if parents[i].Alias != parents[j].Alias {
+			return parents[i].Alias < parents[j].Alias
+		}
+		return parents[i].Type < parents[j].Type
+	})
+	via, pathMin, pathMax := s.pathBounds(n)
 	// Build node
 	pn := &PatternNode{
 		Alias:                  n.Properties["Alias"],
 		Type:                   n.Value,
 		Mode:                   n.Properties["Mode"],
+		AnyOf:                  n.Properties["AnyOf"],
+		Collection:             s.isCollection(n),
 		Filters:                filters,
+		References:             references,
+		Via:                    via,
+		PathMin:                pathMin,
+		PathMax:                pathMax,
 		Children:               kids,
+		Parents:                parents,
 		NormalizedFilterFields: normalized,
 	}
 	return pn
@@ -588,6 +1131,8 @@ func (s *Scheduler) compilePatternNode(n transport.TransportEntity) *PatternNode
 // InvalidatePattern removes a compiled pattern from cache (used on re-registration).
 func (s *Scheduler) InvalidatePattern(actionName string, depID int) {
 	key := actionName + "|" + strconv.Itoa(depID)
+	s.patternMutex.Lock()
+	defer s.patternMutex.Unlock()
 	delete(s.patternCache, key)
 	delete(s.patternSummarized, key)
 	s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling PATTERN invalidated key=", key)
@@ -619,6 +1164,37 @@ func (s *Scheduler) hasDuplicateAliases(root *PatternNode) bool {
 	return dup
 }
 
+// validateReferences checks that every reference of the pattern points to an
+// alias or type that exists in it
+func (s *Scheduler) validateReferences(root *PatternNode) error {
+	names := map[string]bool{}
+	var nodes []*PatternNode
+	var walk func(n *PatternNode)
+	walk = func(n *PatternNode) {
+		names[n.Type] = true
+		if "" != n.Alias {
+			names[n.Alias] = true
+		}
+		nodes = append(nodes, n)
+		for _, related := range append(n.Children, n.Parents...) {
+			walk(related)
+		}
+	}
+	walk(root)
+	for _, n := range nodes {
+		for name, reference := range n.References {
+			parts := strings.SplitN(reference[2], ".", 2)
+			if 2 != len(parts) {
+				return errors.New("reference filter '" + name + "' on '" + n.Type + "' has an invalid reference '" + reference[2] + "'")
+			}
+			if !names[parts[0]] {
+				return errors.New("reference filter '" + name + "' on '" + n.Type + "' references unknown alias '" + parts[0] + "'")
+			}
+		}
+	}
+	return nil
+}
+
 // collectSlotLabels returns child labels (alias or type) for summary logging.
 func (s *Scheduler) collectSlotLabels(root *PatternNode) []string {
 	if root == nil {
@@ -665,6 +1241,33 @@ func (s *Scheduler) DebugGetCompiledPattern(cortex *Cortex, actionName, depName
 	return s.getOrCompilePattern(actionName, dep)
 }
 
+// isRelevantUpdate checks if the updated property keys can lead to a new job for
+// the action dependency. Filter fields always can. Without an idempotency key,
+// every property can in IDEMPOTENCY_CONTENT mode and the selected ones in
+// IDEMPOTENCY_FIELDS mode, since the witness of the updated input differs
+func (s *Scheduler) isRelevantUpdate(act *Action, requirement transport.TransportEntity, updatedKeys string) bool {
+	if s.hasRelevantFilter(requirement, updatedKeys) {
+		return true
+	}
+	if 0 < len(act.GetIdempotencyKey()) {
+		return false
+	}
+	mode, fields := act.GetIdempotency()
+	switch mode {
+	case "Content":
+		s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED RELEVANCE content mode keys=", updatedKeys)
+		return true
+	case "Fields":
+		for _, key := range strings.Split(updatedKeys, ",") {
+			if util.StringInArray(fields, "Properties."+strings.TrimSpace(key)) {
+				s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED RELEVANCE matchedField=", key)
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // hasRelevantFilter checks if any updated property key is used as a filter in the dependency structure
 func (s *Scheduler) hasRelevantFilter(requirement transport.TransportEntity, updatedKeys string) bool {
 	// Collect all filters from the requirement structure
@@ -717,10 +1320,7 @@ func (s *Scheduler) rCollectFilters(entity transport.TransportEntity, filters ma
 func (s *Scheduler) enrichLookupAndPointerByRelationStructures(newRelationStructures map[string][2]*transport.TransportEntity, lookup map[string]int, pointer [][]*transport.TransportEntity) (map[string]int, [][]*transport.TransportEntity) {
 	for _, entityPair := range newRelationStructures {
 		for _, entity := range entityPair {
-			if _, ok := lookup[entity.Type]; !ok {
-				pointer = append(pointer, []*transport.TransportEntity{entity})
-				lookup[entity.Type] = len(pointer) - 1
-			}
+			lookup, pointer = s.addToLookupAndPointer(entity, lookup, pointer)
 		}
 
 	}
@@ -758,6 +1358,9 @@ func (s *Scheduler) collectUpdatedEntityIDs(entity transport.TransportEntity, ne
 		for _, ch := range e.ChildRelations {
 			walk(ch.Target)
 		}
+		for _, pr := range e.ParentRelations {
+			walk(pr.Target)
+		}
 	}
 	walk(entity)
 	// relation-only updates: mark only the child endpoint as updated to avoid
@@ -784,8 +1387,14 @@ func (s *Scheduler) inputContainsUpdated(input *transport.TransportEntity, updat
 			found = true
 			return
 		}
-		for _, ch := range e.ChildRelations {
-			walk(ch.Target)
+		for _, relation := range append(e.ChildRelations, e.ParentRelations...) {
+			for _, hop := range s.pathHops(relation) {
+				if updated[hop.ID] {
+					found = true
+					return
+				}
+			}
+			walk(relation.Target)
 			if found {
 				return
 			}
@@ -795,40 +1404,892 @@ func (s *Scheduler) inputContainsUpdated(input *transport.TransportEntity, updat
 	return found
 }
 
-func (s *Scheduler) buildInputData(requirement transport.TransportEntity, lookup map[string]int, pointer [][]*transport.TransportEntity) []transport.TransportEntity {
+func (s *Scheduler) buildInputData(requirement transport.TransportEntity, lookup map[string]int, pointer [][]*transport.TransportEntity, ts *traceScope) []transport.TransportEntity {
 	newJobs := []transport.TransportEntity{}
 	qry := s.rBuildQuery(requirement, lookup, pointer)
 	result := s.memory.Gits.Query().Execute(qry)
 
 	if 0 < result.Amount {
-		for _, enriched := range result.Entities {
-			newJobs = append(newJobs, s.demultiplexer.Parse(enriched)...)
-		}
+		newJobs = s.parseInputs(requirement, result.Entities, ts)
 	}
 	return newJobs
 }
 
+// parseInputs demultiplexes the query results into single inputs, splits them
+// into one input per satisfied any-of branch and drops those violating an
+// absence condition of the requirement. Dropped inputs are recorded in the
+// trace scope, if given
+func (s *Scheduler) parseInputs(requirement transport.TransportEntity, entities []transport.TransportEntity, ts *traceScope) []transport.TransportEntity {
+	inputs := []transport.TransportEntity{}
+	anyOf := s.hasAnyOf(requirement)
+	collections := s.hasCollections(requirement)
+	// demultiplexed inputs differing only in another branch collapse
+	// into the same input once split, so we only keep the first
+	seen := map[string]bool{}
+	paths := s.hasPaths(requirement)
+	relationFilters := s.hasRelationFilters(requirement)
+	references := s.hasReferences(requirement)
+	for _, enriched := range entities {
+		// hops of path nodes are replaced by direct relations to the reached entities
+		if paths && !s.flattenPaths(requirement, &enriched) {
+			s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED PATH unreached root=", enriched.Type, ":", enriched.ID)
+			ts.record(TRACE_STEP_PATH, false, &enriched, "")
+			continue
+		}
+		// relations not matching the relation filters are removed up front
+		if relationFilters && !s.pruneRelations(requirement, &enriched) {
+			s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED RELATION mismatch root=", enriched.Type, ":", enriched.ID)
+			ts.record(TRACE_STEP_RELATION, false, &enriched, "")
+			continue
+		}
+		detached := map[string][]transport.TransportRelation{}
+		if collections {
+			s.detachCollections(requirement, &enriched, enriched.Type+"#"+strconv.Itoa(enriched.ID), detached)
+		}
+		for _, demultiplexed := range s.demultiplexer.Parse(enriched) {
+			if collections {
+				s.attachCollections(requirement, &demultiplexed, demultiplexed.Type+"#"+strconv.Itoa(demultiplexed.ID), detached)
+				if !s.satisfiesCardinality(requirement, demultiplexed) {
+					s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED COLLECTION out of bounds root=", demultiplexed.Type, ":", demultiplexed.ID)
+					ts.record(TRACE_STEP_COLLECTION, false, &demultiplexed, "")
+					continue
+				}
+			}
+			branches := []transport.TransportEntity{demultiplexed}
+			if anyOf {
+				branches = s.expandAnyOf(requirement, demultiplexed)
+			}
+			for _, input := range branches {
+				if anyOf {
+					signature := util.GenerateIdentitySignature(input)
+					if seen[signature] {
+						continue
+					}
+					seen[signature] = true
+				}
+				if references && !s.satisfiesReferences(requirement, input) {
+					s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED REFERENCE mismatch root=", input.Type, ":", input.ID)
+					ts.record(TRACE_STEP_REFERENCE, false, &input, "")
+					continue
+				}
+				if !s.satisfiesAbsence(requirement, input) {
+					s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED ABSENCE violated root=", input.Type, ":", input.ID)
+					ts.record(TRACE_STEP_ABSENCE, false, &input, "")
+					continue
+				}
+				inputs = append(inputs, input)
+			}
+		}
+	}
+	return inputs
+}
+
+// hasAnyOf returns true if the requirement contains any-of groups
+func (s *Scheduler) hasAnyOf(requirement transport.TransportEntity) bool {
+	for _, related := range append(requirement.Children(), requirement.Parents()...) {
+		if "" != related.Properties["AnyOf"] || s.hasAnyOf(related) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandAnyOf splits an input into one input per combination of any-of
+// branches. Each group of the requirement keeps exactly one of its existing
+// alternatives, an input without any alternative of a group is dropped.
+// Groups are resolved separately for children and parents
+func (s *Scheduler) expandAnyOf(requirement transport.TransportEntity, input transport.TransportEntity) []transport.TransportEntity {
+	type branch struct {
+		relation transport.TransportRelation
+		parent   bool
+	}
+	groupOf := map[string]string{}
+	var groups []string
+	register := func(related []transport.TransportEntity, prefix string) {
+		for _, node := range related {
+			if group := node.Properties["AnyOf"]; "" != group {
+				groupOf[prefix+node.Value] = prefix + group
+				if !util.StringInArray(groups, prefix+group) {
+					groups = append(groups, prefix+group)
+				}
+			}
+		}
+	}
+	register(requirement.Children(), "")
+	register(requirement.Parents(), "<")
+	// every slot holds the alternatives of which exactly one ends up in an input
+	var slots [][]branch
+	groupSlots := map[string][]branch{}
+	collect := func(relations []transport.TransportRelation, related []transport.TransportEntity, parent bool, prefix string) {
+		for _, relation := range relations {
+			variants := []transport.TransportEntity{relation.Target}
+			for _, node := range related {
+				if node.Value == relation.Target.Type && "Absent" != node.Properties["Mode"] {
+					variants = s.expandAnyOf(node, relation.Target)
+					break
+				}
+			}
+			branches := make([]branch, 0, len(variants))
+			for _, variant := range variants {
+				expanded := relation
+				expanded.Target = variant
+				branches = append(branches, branch{relation: expanded, parent: parent})
+			}
+			if group, ok := groupOf[prefix+relation.Target.Type]; ok {
+				groupSlots[group] = append(groupSlots[group], branches...)
+				continue
+			}
+			slots = append(slots, branches)
+		}
+	}
+	collect(input.ChildRelations, requirement.Children(), false, "")
+	collect(input.ParentRelations, requirement.Parents(), true, "<")
+	for _, group := range groups {
+		if 0 == len(groupSlots[group]) {
+			return nil
+		}
+		slots = append(slots, groupSlots[group])
+	}
+	combinations := [][]branch{{}}
+	for _, slot := range slots {
+		var next [][]branch
+		for _, combination := range combinations {
+			for _, alternative := range slot {
+				extended := make([]branch, len(combination), len(combination)+1)
+				copy(extended, combination)
+				next = append(next, append(extended, alternative))
+			}
+		}
+		combinations = next
+	}
+	ret := make([]transport.TransportEntity, 0, len(combinations))
+	for _, combination := range combinations {
+		expanded := input
+		expanded.Properties = util.CopyStringStringMap(input.Properties)
+		expanded.ChildRelations = nil
+		expanded.ParentRelations = nil
+		for _, alternative := range combination {
+			if alternative.parent {
+				expanded.ParentRelations = append(expanded.ParentRelations, alternative.relation)
+				continue
+			}
+			expanded.ChildRelations = append(expanded.ChildRelations, alternative.relation)
+		}
+		ret = append(ret, expanded)
+	}
+	return ret
+}
+
+// hasRelationFilters returns true if the requirement constrains any relation
+func (s *Scheduler) hasRelationFilters(requirement transport.TransportEntity) bool {
+	for _, related := range append(requirement.Children(), requirement.Parents()...) {
+		if 0 < len(s.relationFilters(related)) || s.hasRelationFilters(related) {
+			return true
+		}
+	}
+	return false
+}
+
+// relationFilters returns the relation filters of a structure node including
+// its relation context as [Field, Operator, Value]
+func (s *Scheduler) relationFilters(node transport.TransportEntity) [][3]string {
+	var filters [][3]string
+	if context := node.Properties["RelationContext"]; "" != context {
+		filters = append(filters, [3]string{"Context", "==", context})
+	}
+	return append(filters, s.prefixedFilters(node, "RelationFilter")...)
+}
+
+// prefixedFilters returns the filters stored as <prefix>.<name>.(Field|Operator|Value)
+// on the structure node as [Field, Operator, Value], ordered by name
+func (s *Scheduler) prefixedFilters(node transport.TransportEntity, prefix string) [][3]string {
+	byName := map[string]*[3]string{}
+	var names []string
+	for name, val := range node.Properties {
+		splitName := strings.Split(name, ".")
+		if len(splitName) != 3 || prefix != splitName[0] {
+			continue
+		}
+		if _, ok := byName[splitName[1]]; !ok {
+			byName[splitName[1]] = &[3]string{}
+			names = append(names, splitName[1])
+		}
+		switch splitName[2] {
+		case "Field":
+			byName[splitName[1]][0] = val
+		case "Operator":
+			byName[splitName[1]][1] = val
+		case "Value":
+			byName[splitName[1]][2] = val
+		}
+	}
+	sort.Strings(names)
+	filters := make([][3]string, 0, len(names))
+	for _, name := range names {
+		filters = append(filters, *byName[name])
+	}
+	return filters
+}
+
+// hasReferences returns true if the structure node or any node related to it
+// has reference filters
+func (s *Scheduler) hasReferences(node transport.TransportEntity) bool {
+	if 0 < len(s.prefixedFilters(node, "Reference")) {
+		return true
+	}
+	for _, related := range append(node.Children(), node.Parents()...) {
+		if s.hasReferences(related) {
+			return true
+		}
+	}
+	return false
+}
+
+// satisfiesReferences walks the input alongside the requirement and compares
+// every entity with a reference filter to the entity its reference points to
+func (s *Scheduler) satisfiesReferences(requirement transport.TransportEntity, input transport.TransportEntity) bool {
+	return s.rSatisfiesReferences(requirement, requirement, input, input)
+}
+
+func (s *Scheduler) rSatisfiesReferences(root transport.TransportEntity, node transport.TransportEntity, input transport.TransportEntity, entity transport.TransportEntity) bool {
+	for _, filter := range s.prefixedFilters(node, "Reference") {
+		parts := strings.SplitN(filter[2], ".", 2)
+		if 2 != len(parts) {
+			return false
+		}
+		typeName := parts[0]
+		if aliased := s.findNodeByAlias(root, parts[0]); nil != aliased {
+			typeName = aliased.Value
+		}
+		target, ok := s.findFirstInInputByType(&input, typeName)
+		if !ok || !util.MatchValue(util.ResolveEntityField(entity, filter[0]), filter[1], util.ResolveEntityField(*target, parts[1])) {
+			return false
+		}
+	}
+	walk := func(related []transport.TransportEntity, relations []transport.TransportRelation) bool {
+		for _, relatedNode := range related {
+			for _, relation := range relations {
+				if relation.Target.Type == relatedNode.Value && !s.rSatisfiesReferences(root, relatedNode, input, relation.Target) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return walk(node.Children(), entity.ChildRelations) && walk(node.Parents(), entity.ParentRelations)
+}
+
+// matchesRelation checks the relation leading to an entity against the
+// relation filters of its structure node
+func (s *Scheduler) matchesRelation(node transport.TransportEntity, relation transport.TransportRelation) bool {
+	for _, filter := range s.relationFilters(node) {
+		if !util.MatchRelationField(relation, filter[0], filter[1], filter[2]) {
+			return false
+		}
+	}
+	return true
+}
+
+// pruneRelations removes the relations of the entity that don't match the
+// relation filters of the requirement. Returns false if a required node has no
+// matching relation left
+func (s *Scheduler) pruneRelations(requirement transport.TransportEntity, entity *transport.TransportEntity) bool {
+	var children, parents bool
+	entity.ChildRelations, children = s.pruneRelationsIn(requirement.Children(), entity.ChildRelations)
+	entity.ParentRelations, parents = s.pruneRelationsIn(requirement.Parents(), entity.ParentRelations)
+	return children && parents
+}
+
+// pruneRelationsIn prunes the relations of one direction
+func (s *Scheduler) pruneRelationsIn(related []transport.TransportEntity, relations []transport.TransportRelation) ([]transport.TransportRelation, bool) {
+	var kept []transport.TransportRelation
+	for _, relation := range relations {
+		matched := false
+		known := false
+		for _, node := range related {
+			if node.Value != relation.Target.Type || "Absent" == node.Properties["Mode"] {
+				continue
+			}
+			known = true
+			if s.matchesRelation(node, relation) && s.pruneRelations(node, &relation.Target) {
+				matched = true
+				break
+			}
+		}
+		if matched || !known {
+			kept = append(kept, relation)
+		}
+	}
+	for _, node := range related {
+		if "Absent" == node.Properties["Mode"] || "" != node.Properties["AnyOf"] {
+			continue
+		}
+		if min, _ := s.collectionBounds(node); s.isCollection(node) && 0 == min {
+			continue
+		}
+		found := false
+		for _, relation := range kept {
+			if relation.Target.Type == node.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return kept, false
+		}
+	}
+	return kept, true
+}
+
+// isPath returns true if the structure node is reached through a path
+func (s *Scheduler) isPath(node transport.TransportEntity) bool {
+	return "" != node.Properties["Path.Via"]
+}
+
+// pathBounds returns the via types and the min and max amount of hops of a
+// path node
+func (s *Scheduler) pathBounds(node transport.TransportEntity) ([]string, int, int) {
+	if !s.isPath(node) {
+		return nil, 0, 0
+	}
+	min, _ := strconv.Atoi(node.Properties["Path.Min"])
+	max, _ := strconv.Atoi(node.Properties["Path.Max"])
+	return strings.Split(node.Properties["Path.Via"], ","), min, max
+}
+
+// hasPaths returns true if the requirement contains path nodes
+func (s *Scheduler) hasPaths(requirement transport.TransportEntity) bool {
+	for _, related := range append(requirement.Children(), requirement.Parents()...) {
+		if s.isPath(related) || s.hasPaths(related) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathHops returns the entities a flattened path relation traversed, stored
+// as Type:ID list in its Path property
+func (s *Scheduler) pathHops(relation transport.TransportRelation) []transport.TransportEntity {
+	var hops []transport.TransportEntity
+	if "" == relation.Properties["Path"] {
+		return hops
+	}
+	for _, hop := range strings.Split(relation.Properties["Path"], ",") {
+		separator := strings.LastIndex(hop, ":")
+		if -1 == separator {
+			continue
+		}
+		id, err := strconv.Atoi(hop[separator+1:])
+		if nil != err {
+			continue
+		}
+		hops = append(hops, transport.TransportEntity{Type: hop[:separator], ID: id})
+	}
+	return hops
+}
+
+// flattenPaths replaces the hops matched for path nodes by direct relations to
+// the reached entities. The traversed entities are kept as Type:ID list in the
+// Path property of the relation and their amount in Hops, only the shortest path
+// per reached entity is kept. Returns false if a path node reached no entity
+func (s *Scheduler) flattenPaths(requirement transport.TransportEntity, entity *transport.TransportEntity) bool {
+	var children, parents bool
+	entity.ChildRelations, children = s.flattenPathsIn(requirement.Children(), entity.ChildRelations, false)
+	entity.ParentRelations, parents = s.flattenPathsIn(requirement.Parents(), entity.ParentRelations, true)
+	return children && parents
+}
+
+// flattenPathsIn flattens the paths of one direction
+func (s *Scheduler) flattenPathsIn(related []transport.TransportEntity, relations []transport.TransportRelation, parent bool) ([]transport.TransportRelation, bool) {
+	// types matched by regular nodes keep their relations
+	regular := map[string]transport.TransportEntity{}
+	for _, node := range related {
+		if !s.isPath(node) && "Absent" != node.Properties["Mode"] {
+			if _, known := regular[node.Value]; !known {
+				regular[node.Value] = node
+			}
+		}
+	}
+	consumed := map[string]bool{}
+	var flattened []transport.TransportRelation
+	valid := true
+	for _, node := range related {
+		if !s.isPath(node) || "Absent" == node.Properties["Mode"] {
+			continue
+		}
+		via, min, max := s.pathBounds(node)
+		reached := map[int]int{}
+		var found []transport.TransportRelation
+		var walk func(relations []transport.TransportRelation, path []string)
+		walk = func(relations []transport.TransportRelation, path []string) {
+			for _, relation := range relations {
+				if relation.Target.Type == node.Value && len(path) >= min {
+					flat := relation
+					flat.Properties = util.CopyStringStringMap(relation.Properties)
+					flat.Properties["Path"] = strings.Join(path, ",")
+					flat.Properties["Hops"] = strconv.Itoa(len(path))
+					if s.flattenPaths(node, &flat.Target) {
+						if idx, ok := reached[flat.Target.ID]; !ok {
+							reached[flat.Target.ID] = len(found)
+							found = append(found, flat)
+						} else if len(s.pathHops(found[idx])) > len(path) {
+							found[idx] = flat
+						}
+					}
+				}
+				if util.StringInArray(via, relation.Target.Type) && len(path) < max {
+					next := relation.Target.ChildRelations
+					if parent {
+						next = relation.Target.ParentRelations
+					}
+					walk(next, append(path[:len(path):len(path)], relation.Target.Type+":"+strconv.Itoa(relation.Target.ID)))
+				}
+			}
+		}
+		walk(relations, nil)
+		consumed[node.Value] = true
+		for _, typeName := range via {
+			consumed[typeName] = true
+		}
+		if 0 == len(found) {
+			valid = false
+		}
+		flattened = append(flattened, found...)
+	}
+	// paths further down are flattened as well, a relation whose paths can't
+	// be reached is dropped and invalidates the entity if it was the last one
+	var kept []transport.TransportRelation
+	dropped := map[string]bool{}
+	remaining := map[string]bool{}
+	for _, relation := range relations {
+		node, ok := regular[relation.Target.Type]
+		if !ok {
+			if !consumed[relation.Target.Type] {
+				kept = append(kept, relation)
+			}
+			continue
+		}
+		if !s.flattenPaths(node, &relation.Target) {
+			dropped[relation.Target.Type] = true
+			continue
+		}
+		remaining[relation.Target.Type] = true
+		kept = append(kept, relation)
+	}
+	for typeName := range dropped {
+		if !remaining[typeName] {
+			valid = false
+		}
+	}
+	return append(kept, flattened...), valid
+}
+
+// isCollection returns true if the structure node is flagged as collection
+func (s *Scheduler) isCollection(node transport.TransportEntity) bool {
+	_, ok := node.Properties["Collection.Min"]
+	return ok
+}
+
+// collectionBounds returns the min and max amount of entities of a collection
+// node, a max of 0 means unbounded
+func (s *Scheduler) collectionBounds(node transport.TransportEntity) (int, int) {
+	min, _ := strconv.Atoi(node.Properties["Collection.Min"])
+	max, _ := strconv.Atoi(node.Properties["Collection.Max"])
+	return min, max
+}
+
+// hasCollections returns true if the requirement contains collection nodes
+func (s *Scheduler) hasCollections(requirement transport.TransportEntity) bool {
+	for _, related := range append(requirement.Children(), requirement.Parents()...) {
+		if s.isCollection(related) || s.hasCollections(related) {
+			return true
+		}
+	}
+	return false
+}
+
+// detachCollections removes the members of collection nodes from the entity so
+// the demultiplexer doesn't split them into one input each. The members are
+// stored by the position of the entity they belong to, path, and are attached to
+// every demultiplexed input again by attachCollections. Members found twice are
+// merged keeping the one with more relations
+func (s *Scheduler) detachCollections(requirement transport.TransportEntity, entity *transport.TransportEntity, path string, detached map[string][]transport.TransportRelation) {
+	entity.ChildRelations = s.detachCollectionsIn(requirement.Children(), entity.ChildRelations, path+">", detached)
+	entity.ParentRelations = s.detachCollectionsIn(requirement.Parents(), entity.ParentRelations, path+"<", detached)
+}
+
+// detachCollectionsIn detaches the collections of one direction and returns the
+// relations that are kept
+func (s *Scheduler) detachCollectionsIn(related []transport.TransportEntity, relations []transport.TransportRelation, prefix string, detached map[string][]transport.TransportRelation) []transport.TransportRelation {
+	var kept []transport.TransportRelation
+	for _, relation := range relations {
+		node, ok := s.findRelatedNode(related, relation.Target.Type)
+		if !ok {
+			kept = append(kept, relation)
+			continue
+		}
+		key := prefix + relation.Target.Type
+		if !s.isCollection(node) {
+			s.detachCollections(node, &relation.Target, key+"#"+strconv.Itoa(relation.Target.ID), detached)
+			kept = append(kept, relation)
+			continue
+		}
+		merged := false
+		for i, member := range detached[key] {
+			if member.Target.ID == relation.Target.ID {
+				if len(relation.Target.ChildRelations)+len(relation.Target.ParentRelations) > len(member.Target.ChildRelations)+len(member.Target.ParentRelations) {
+					detached[key][i] = relation
+				}
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			detached[key] = append(detached[key], relation)
+		}
+	}
+	return kept
+}
+
+// attachCollections adds copies of the detached collection members back to the
+// entity and the related entities of the input
+func (s *Scheduler) attachCollections(requirement transport.TransportEntity, entity *transport.TransportEntity, path string, detached map[string][]transport.TransportRelation) {
+	entity.ChildRelations = s.attachCollectionsIn(requirement.Children(), entity.ChildRelations, path+">", detached)
+	entity.ParentRelations = s.attachCollectionsIn(requirement.Parents(), entity.ParentRelations, path+"<", detached)
+}
+
+// attachCollectionsIn attaches the collections of one direction
+func (s *Scheduler) attachCollectionsIn(related []transport.TransportEntity, relations []transport.TransportRelation, prefix string, detached map[string][]transport.TransportRelation) []transport.TransportRelation {
+	for i := range relations {
+		if node, ok := s.findRelatedNode(related, relations[i].Target.Type); ok {
+			s.attachCollections(node, &relations[i].Target, prefix+relations[i].Target.Type+"#"+strconv.Itoa(relations[i].Target.ID), detached)
+		}
+	}
+	for _, node := range related {
+		if !s.isCollection(node) {
+			continue
+		}
+		for _, member := range detached[prefix+node.Value] {
+			member.Target = s.demultiplexer.deepCopyEntity(member.Target)
+			relations = append(relations, member)
+		}
+	}
+	return relations
+}
+
+// findRelatedNode returns the first present structure node of the given type
+func (s *Scheduler) findRelatedNode(related []transport.TransportEntity, typeName string) (transport.TransportEntity, bool) {
+	for _, node := range related {
+		if node.Value == typeName && "Absent" != node.Properties["Mode"] {
+			return node, true
+		}
+	}
+	return transport.TransportEntity{}, false
+}
+
+// satisfiesCardinality walks the input alongside the requirement and checks that
+// the amount of entities of every collection is within its bounds
+func (s *Scheduler) satisfiesCardinality(requirement transport.TransportEntity, input transport.TransportEntity) bool {
+	return s.satisfiesCardinalityIn(requirement.Children(), input.ChildRelations) &&
+		s.satisfiesCardinalityIn(requirement.Parents(), input.ParentRelations)
+}
+
+// satisfiesCardinalityIn checks the related structures of one direction
+func (s *Scheduler) satisfiesCardinalityIn(related []transport.TransportEntity, relations []transport.TransportRelation) bool {
+	for _, node := range related {
+		if "Absent" == node.Properties["Mode"] {
+			continue
+		}
+		amount := 0
+		for _, relation := range relations {
+			if relation.Target.Type != node.Value {
+				continue
+			}
+			amount++
+			if !s.satisfiesCardinality(node, relation.Target) {
+				return false
+			}
+		}
+		if s.isCollection(node) {
+			min, max := s.collectionBounds(node)
+			if amount < min || (0 < max && amount > max) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// satisfiesAbsence walks the input alongside the requirement and checks that
+// none of the absent nodes exists next to the matching input entity
+func (s *Scheduler) satisfiesAbsence(requirement transport.TransportEntity, input transport.TransportEntity) bool {
+	return s.satisfiesAbsenceIn(requirement.Children(), input, input.ChildRelations, false) &&
+		s.satisfiesAbsenceIn(requirement.Parents(), input, input.ParentRelations, true)
+}
+
+// satisfiesAbsenceIn checks the related structures of one direction
+func (s *Scheduler) satisfiesAbsenceIn(related []transport.TransportEntity, input transport.TransportEntity, relations []transport.TransportRelation, parent bool) bool {
+	for _, node := range related {
+		if "Absent" == node.Properties["Mode"] {
+			if s.absentNodeExists(input, node, parent) {
+				return false
+			}
+			continue
+		}
+		for _, relation := range relations {
+			if relation.Target.Type == node.Value && !s.satisfiesAbsence(node, relation.Target) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// absentNodeExists checks if the entity has a child, or a parent if
+// parent is given, matching the absent node
+func (s *Scheduler) absentNodeExists(entity transport.TransportEntity, absent transport.TransportEntity, parent bool) bool {
+	absentQry := s.enrichQueryFilters(query.New().Read(absent.Value), absent)
+	absentQry = s.addRelatedQueries(absentQry, absent.ChildRelations, false, map[string]int{}, nil)
+	absentQry = s.addRelatedQueries(absentQry, absent.ParentRelations, true, map[string]int{}, nil)
+	qry := query.New().Read(entity.Type).Match("ID", "==", strconv.Itoa(entity.ID))
+	if parent {
+		qry = qry.From(absentQry)
+	} else {
+		qry = qry.To(absentQry)
+	}
+	result := s.memory.Gits.Query().Execute(qry)
+	for _, found := range result.Entities {
+		relations := found.ChildRelations
+		if parent {
+			relations = found.ParentRelations
+		}
+		for _, relation := range relations {
+			if s.matchesRelation(absent, relation) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// revokeAbsentJobs is called when an entity appeared that an absent node of the
+// requirement describes. Open jobs of the dependency whose input contains an entity
+// the new one is related to are re-evaluated and revoked if their absence condition
+// broke. The witness of a revoked job is deleted, so the input is scheduled again if
+// the condition holds at some later point. Returns the amount of revoked jobs
+func (s *Scheduler) revokeAbsentJobs(act *Action, depName string, requirement transport.TransportEntity, entity transport.TransportEntity) int {
+	neighbours := map[string]bool{}
+	for _, neighbour := range s.collectAbsentNeighbours(requirement.Children()[0], entity.Type) {
+		entityQry := query.New().Read(entity.Type).Match("ID", "==", strconv.Itoa(entity.ID))
+		qry := query.New().Read(neighbour.Type)
+		if neighbour.AbsentParent {
+			qry = qry.From(entityQry)
+		} else {
+			qry = qry.To(entityQry)
+		}
+		for _, related := range s.memory.Gits.Query().Execute(qry).Entities {
+			neighbours[related.Type+":"+strconv.Itoa(related.ID)] = true
+		}
+	}
+	if 0 == len(neighbours) {
+		return 0
+	}
+	jobQry := query.New().Read("Job").Match("Context", "==", s.memory.Scope("System")).Match("Properties.Action", "==", act.GetName()).Match("Properties.Requirement", "==", depName).To(
+		query.New().Read("State").Match("Value", "==", "Open").Match("Context", "==", s.memory.Scope("System")),
+	).To(
+		query.New().Read("Input"),
+	)
+	revoked := 0
+	for _, jobEntity := range s.memory.Gits.Query().Execute(jobQry).Entities {
+		for _, inputEntity := range jobEntity.Children() {
+			if "Input" != inputEntity.Type {
+				continue
+			}
+			var input transport.TransportEntity
+			if err := json.Unmarshal([]byte(inputEntity.Properties["Data"]), &input); nil != err {
+				s.log.Error("Could not decode input of job", jobEntity.ID)
+				continue
+			}
+			affected := false
+			s.rWalkInput(&input, func(e *transport.TransportEntity) {
+				if neighbours[e.Type+":"+strconv.Itoa(e.ID)] {
+					affected = true
+				}
+			})
+			if !affected || s.satisfiesAbsence(requirement.Children()[0], input) {
+				continue
+			}
+			job := &Job{id: jobEntity.ID, data: jobEntity, memory: s.memory, log: s.log}
+			if job.Revoke() {
+				s.memory.Gits.Query().Execute(query.New().Delete("Memory").Match("Value", "==", jobEntity.Properties["Witness"]).Match("Context", "==", s.memory.Scope("System")))
+				s.log.Debug(archivist.DEBUG_LEVEL_TRACE, "scheduling SCHED ABSENCE revoked job id=", jobEntity.ID, " action=", act.GetName(), " dep=", depName)
+				revoked++
+			}
+		}
+	}
+	return revoked
+}
+
+// absentNeighbour is a structure node with an absent node of a certain type next to it
+type absentNeighbour struct {
+	Type string
+	// AbsentParent is true if the absent node is a parent of the neighbour
+	AbsentParent bool
+}
+
+// collectAbsentNeighbours returns all nodes having an absent child
+// or parent of the given type
+func (s *Scheduler) collectAbsentNeighbours(node transport.TransportEntity, typeName string) []absentNeighbour {
+	var ret []absentNeighbour
+	add := func(neighbour absentNeighbour) {
+		for _, known := range ret {
+			if known == neighbour {
+				return
+			}
+		}
+		ret = append(ret, neighbour)
+	}
+	walk := func(related []transport.TransportEntity, parent bool) {
+		for _, relatedNode := range related {
+			if "Absent" == relatedNode.Properties["Mode"] {
+				if relatedNode.Value == typeName {
+					add(absentNeighbour{Type: node.Value, AbsentParent: parent})
+				}
+				continue
+			}
+			for _, neighbour := range s.collectAbsentNeighbours(relatedNode, typeName) {
+				add(neighbour)
+			}
+		}
+	}
+	walk(node.Children(), false)
+	walk(node.Parents(), true)
+	return ret
+}
+
 func (s *Scheduler) rBuildQuery(requirement transport.TransportEntity, lookup map[string]int, pointer [][]*transport.TransportEntity) *query.Query {
 	qry := query.New().Read(requirement.Value)
-	// is requirement in index we add an exact ID matching filter
-	if _, ok := lookup[requirement.Value]; ok {
-		tmpEntity := pointer[lookup[requirement.Value]][0]
-		qry.Match("ID", "==", strconv.Itoa(tmpEntity.ID))
+	// is requirement in index we restrict it to the IDs of the new entities of its type
+	if slot, ok := lookup[requirement.Value]; ok {
+		if 1 == len(pointer[slot]) {
+			qry.Match("ID", "==", strconv.Itoa(pointer[slot][0].ID))
+		} else {
+			ids := make([]string, 0, len(pointer[slot]))
+			for _, tmpEntity := range pointer[slot] {
+				ids = append(ids, strconv.Itoa(tmpEntity.ID))
+			}
+			qry.Match("ID", "in", strings.Join(ids, ","))
+		}
 	}
 	// if its match mode we have to apply filters
 	if requirement.Properties["Mode"] == "Match" {
 		// we add match filters
 		qry = s.enrichQueryFilters(qry, requirement)
 	}
-	// any child relations?
-	if 0 < len(requirement.ChildRelations) {
-		for _, childRelation := range requirement.ChildRelations {
-			qry = qry.To(s.rBuildQuery(childRelation.Target, lookup, pointer))
+	// any child or parent relations?
+	qry = s.addRelatedQueries(qry, requirement.ChildRelations, false, lookup, pointer)
+	qry = s.addRelatedQueries(qry, requirement.ParentRelations, true, lookup, pointer)
+	return qry
+}
+
+// addRelatedQueries adds the sub queries of the related structures, children
+// are matched with To and parents with From
+func (s *Scheduler) addRelatedQueries(qry *query.Query, relations []transport.TransportRelation, parent bool, lookup map[string]int, pointer [][]*transport.TransportEntity) *query.Query {
+	for _, relation := range relations {
+		// absent nodes can't be expressed as query, inputs are checked against them afterwards
+		if "Absent" == relation.Target.Properties["Mode"] {
+			continue
+		}
+		if s.isPath(relation.Target) {
+			qry = s.addPathQueries(qry, relation.Target, parent, lookup, pointer)
+			continue
+		}
+		if s.isCollection(relation.Target) {
+			qry = s.addCollectionQueries(qry, relation.Target, parent, lookup, pointer)
+			continue
+		}
+		sub := s.rBuildQuery(relation.Target, lookup, pointer)
+		// alternatives are optional on their own, parseInputs makes sure one of each group exists
+		optional := "" != relation.Target.Properties["AnyOf"]
+		switch {
+		case parent && optional:
+			qry = qry.CanFrom(sub)
+		case parent:
+			qry = qry.From(sub)
+		case optional:
+			qry = qry.CanTo(sub)
+		default:
+			qry = qry.To(sub)
+		}
+	}
+	return qry
+}
+
+// addPathQueries adds one optional sub query per possible amount of hops of a
+// path node. The hops are matched by their via types without lookup constraints,
+// flattenPaths makes sure the node is reached at all
+func (s *Scheduler) addPathQueries(qry *query.Query, node transport.TransportEntity, parent bool, lookup map[string]int, pointer [][]*transport.TransportEntity) *query.Query {
+	via, min, max := s.pathBounds(node)
+	for hops := min; hops <= max; hops++ {
+		sub := s.rBuildQuery(node, lookup, pointer)
+		for i := 0; i < hops; i++ {
+			if parent {
+				sub = query.New().Read(via...).From(sub)
+			} else {
+				sub = query.New().Read(via...).To(sub)
+			}
+		}
+		if parent {
+			qry = qry.CanFrom(sub)
+		} else {
+			qry = qry.CanTo(sub)
 		}
 	}
 	return qry
 }
 
+// addCollectionQueries adds the sub queries of a collection node. All members are
+// matched without the lookup constraint, if new entities of the batch are part of
+// the collection a second sub query requires them to be among the members
+func (s *Scheduler) addCollectionQueries(qry *query.Query, node transport.TransportEntity, parent bool, lookup map[string]int, pointer [][]*transport.TransportEntity) *query.Query {
+	members := s.rBuildQuery(node, map[string]int{}, nil)
+	required := false
+	if s.structureInLookup(node, lookup) {
+		required = true
+		pinned := s.rBuildQuery(node, lookup, pointer)
+		if parent {
+			qry = qry.From(pinned)
+		} else {
+			qry = qry.To(pinned)
+		}
+	}
+	// an empty collection is fine if the min allows it
+	if min, _ := s.collectionBounds(node); required || 0 == min {
+		if parent {
+			return qry.CanFrom(members)
+		}
+		return qry.CanTo(members)
+	}
+	if parent {
+		return qry.From(members)
+	}
+	return qry.To(members)
+}
+
+// structureInLookup returns true if the node or any node related to it has
+// entities in the lookup
+func (s *Scheduler) structureInLookup(node transport.TransportEntity, lookup map[string]int) bool {
+	if _, ok := lookup[node.Value]; ok {
+		return true
+	}
+	for _, related := range append(node.Children(), node.Parents()...) {
+		if s.structureInLookup(related, lookup) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Scheduler) enrichQueryFilters(query *query.Query, requirement transport.TransportEntity) *query.Query {
 	filters := make(map[string][]string)
 	for name, val := range requirement.Properties {
@@ -862,7 +2323,7 @@ func (s *Scheduler) enrichQueryFilters(query *query.Query, requirement transport
 
 func (s *Scheduler) retrieveActionsByType(entityType string) [][2]string {
 	var ret [][2]string
-	qry := query.New().Read("DependencyEntityLookup").Match("Value", "==", entityType).To(
+	qry := query.New().Read("DependencyEntityLookup").Match("Value", "==", entityType).Match("Context", "==", s.memory.Scope("System")).To(
 		query.New().Read("Dependency").From(
 			query.New().Read("Action"),
 		),
@@ -882,7 +2343,7 @@ func (s *Scheduler) retrieveActionsByType(entityType string) [][2]string {
 
 func (s *Scheduler) retrieveActionsByRelationStructure(relationStructure string) [][2]string {
 	var ret [][2]string
-	qry := query.New().Read("DependencyRelationLookup").Match("Value", "==", relationStructure).To(
+	qry := query.New().Read("DependencyRelationLookup").Match("Value", "==", relationStructure).Match("Context", "==", s.memory.Scope("System")).To(
 		query.New().Read("Dependency").From(
 			query.New().Read("Action"),
 		),
@@ -903,26 +2364,35 @@ func (s *Scheduler) rEnrichLookupAndPointer(entity transport.TransportEntity, lo
 	s.log.Debug(archivist.DEBUG_LEVEL_MAX, "Enrichting step", entity)
 	// lets see if this is newly learned data
 	if _, ok := entity.Properties["bMap"]; ok {
-		// do we already know about this entity type?
-		if _, well := lookup[entity.Type]; !well {
-			// it's not known, so we create wa whole new first level entry on pointer and
-			// also add it to our lookup map for later use
-			s.log.Debug(archivist.DEBUG_LEVEL_MAX, "Adding entity to pointer", entity)
-			pointer = append(pointer, []*transport.TransportEntity{&entity})
-			lookup[entity.Type] = len(pointer) - 1
-		} else {
-			// ### for now we gonne assume we only need the first upcome, later ones we skip. We might need to overthink
-			// this since it hard impacts the scheduler (we would ne to multiplex on feeding into our dependency structure
-			// in case there are same types on different levels. We keep this following line and else just in cae
-			// we need to reactivate it. comments dont hurt
-			//pointer[val] = append(pointer[val], &entity)
-		}
+		// every new entity is kept, so several new entities of the same type in one
+		// batch end up as an ID set instead of only the first one being considered
+		s.log.Debug(archivist.DEBUG_LEVEL_MAX, "Adding entity to pointer", entity)
+		lookup, pointer = s.addToLookupAndPointer(&entity, lookup, pointer)
 	}
 	for _, childRelation := range entity.ChildRelations {
 		lookup, pointer = s.rEnrichLookupAndPointer(childRelation.Target, lookup, pointer)
 	}
-	//for _, parentRelation := range entity.ParentRelations { // ### enrichment towards parents is disabled for now
-	// lookup, pointer = rEnrichLookupAndPointer(parentRelation.Target, lookup, pointer)
-	//}
+	// parents are enriched as well, patterns may point upwards from their root
+	for _, parentRelation := range entity.ParentRelations {
+		lookup, pointer = s.rEnrichLookupAndPointer(parentRelation.Target, lookup, pointer)
+	}
+	return lookup, pointer
+}
+
+// addToLookupAndPointer adds the entity to the pointer slot of its type, creating
+// the slot if the type isn't known yet. Entities already in the slot are skipped
+func (s *Scheduler) addToLookupAndPointer(entity *transport.TransportEntity, lookup map[string]int, pointer [][]*transport.TransportEntity) (map[string]int, [][]*transport.TransportEntity) {
+	slot, ok := lookup[entity.Type]
+	if !ok {
+		pointer = append(pointer, []*transport.TransportEntity{entity})
+		lookup[entity.Type] = len(pointer) - 1
+		return lookup, pointer
+	}
+	for _, known := range pointer[slot] {
+		if known.ID == entity.ID {
+			return lookup, pointer
+		}
+	}
+	pointer[slot] = append(pointer[slot], entity)
 	return lookup, pointer
 }
@@ -0,0 +1,103 @@
+package cerebrum
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/voodooEntity/cyberbrain/src/system/archivist"
+	"github.com/voodooEntity/cyberbrain/src/system/util"
+)
+
+// TIMER_TICK is the resolution periodic actions are checked with
+const TIMER_TICK = time.Second
+
+// TIMER_BATCH_SIZE is the default amount of root entities a periodic
+// run loads at a time
+const TIMER_BATCH_SIZE = 100
+
+// Timer gives the cyberbrain a sense of time. It re-runs actions
+// with an interval against all inputs matching their dependencies
+// and sweeps expired witnesses
+type Timer struct {
+	memory    *Memory
+	cortex    *Cortex
+	scheduler *Scheduler
+	log       *archivist.Archivist
+	// last bucket each periodic action has been run for
+	buckets map[string]int64
+	// amount of root entities a periodic run loads at a time
+	batchSize int
+	// how often expired witnesses are swept, 0 disables sweeping
+	sweepInterval time.Duration
+	lastSweep     time.Time
+}
+
+func NewTimer(memoryInstance *Memory, cortexInstance *Cortex, schedulerInstance *Scheduler, logger *archivist.Archivist) *Timer {
+	return &Timer{
+		memory:    memoryInstance,
+		cortex:    cortexInstance,
+		scheduler: schedulerInstance,
+		log:       logger,
+		buckets:   make(map[string]int64),
+		batchSize: TIMER_BATCH_SIZE,
+	}
+}
+
+// LoopContext ticks until the cyberbrain is terminated or ctx is done
+func (t *Timer) LoopContext(ctx context.Context) {
+	ticker := time.NewTicker(TIMER_TICK)
+	defer ticker.Stop()
+	for util.IsAlive(t.memory.Gits, t.memory.Ident) && nil == ctx.Err() {
+		t.Tick(time.Now())
+		t.Sweep(time.Now())
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+	t.log.Info("Cyberbrain has been shutdown, timer exiting")
+}
+
+// Tick runs every periodic action that entered a new interval bucket
+// since the last tick. Returns the amount of jobs created
+func (t *Timer) Tick(now time.Time) int {
+	created := 0
+	for _, act := range t.cortex.GetActions() {
+		interval := act.GetInterval()
+		if 0 >= interval {
+			continue
+		}
+		bucket := now.UnixMilli() / interval.Milliseconds()
+		if last, ok := t.buckets[act.GetName()]; ok && last == bucket {
+			continue
+		}
+		t.buckets[act.GetName()] = bucket
+		amount := t.scheduler.RunPeriodic(act, strconv.FormatInt(bucket, 10), t.batchSize)
+		t.log.Debug(archivist.DEBUG_LEVEL_INFO, "Timer created periodic jobs", act.GetName(), amount)
+		created += amount
+	}
+	return created
+}
+
+// SetBatchSize sets the amount of root entities a periodic run
+// loads at a time
+func (t *Timer) SetBatchSize(batchSize int) {
+	t.batchSize = batchSize
+}
+
+// SetSweepInterval sets how often expired witnesses are
+// garbage collected, 0 disables sweeping
+func (t *Timer) SetSweepInterval(interval time.Duration) {
+	t.sweepInterval = interval
+}
+
+// Sweep deletes expired witnesses once the sweep interval passed
+// since the last sweep. Returns the amount of witnesses deleted
+func (t *Timer) Sweep(now time.Time) int {
+	if 0 >= t.sweepInterval || now.Sub(t.lastSweep) < t.sweepInterval {
+		return 0
+	}
+	t.lastSweep = now
+	return t.scheduler.SweepWitnesses(now)
+}
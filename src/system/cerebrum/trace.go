@@ -0,0 +1,168 @@
+package cerebrum
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/voodooEntity/gits/src/transport"
+)
+
+// Steps a trace decision can be taken in, in the order the scheduler runs them
+const (
+	// TRACE_STEP_LOOKUP the entity type is not mapped to the dependency, only
+	// recorded when explaining since scheduling doesn't see those dependencies
+	TRACE_STEP_LOOKUP = "lookup"
+	// TRACE_STEP_PATTERN the anchor type is not part of the dependency
+	TRACE_STEP_PATTERN = "pattern"
+	// TRACE_STEP_RELEVANCE the updated properties are neither filter fields nor
+	// considered by the idempotency mode
+	TRACE_STEP_RELEVANCE = "relevance"
+	// TRACE_STEP_QUERY the amount of inputs built for the anchor
+	TRACE_STEP_QUERY = "query"
+	// TRACE_STEP_PATH the input doesn't reach the end of a path node
+	TRACE_STEP_PATH = "path"
+	// TRACE_STEP_RELATION a required node has no relation left matching the
+	// relation filters
+	TRACE_STEP_RELATION = "relation"
+	// TRACE_STEP_COLLECTION the amount of collected entities is out of the
+	// bounds of a collection node
+	TRACE_STEP_COLLECTION = "collection"
+	// TRACE_STEP_REFERENCE a reference between nodes of the input doesn't hold
+	TRACE_STEP_REFERENCE = "reference"
+	// TRACE_STEP_ABSENCE an absent node exists next to the input
+	TRACE_STEP_ABSENCE = "absence"
+	// TRACE_STEP_ANCHOR the input doesn't contain the anchor
+	TRACE_STEP_ANCHOR = "anchor"
+	// TRACE_STEP_CAUSALITY the input doesn't contain data updated by the batch
+	TRACE_STEP_CAUSALITY = "causality"
+	// TRACE_STEP_WITNESS the input already ran, detail holds the witness signature
+	TRACE_STEP_WITNESS = "witness"
+	// TRACE_STEP_JOB a job has been created, detail holds its id, or would be
+	// created when explaining
+	TRACE_STEP_JOB = "job"
+)
+
+// Trace records the decisions the scheduler took for a batch, or for a single
+// entity when explained, so why an action did or didn't run can be checked
+// without reading the trace logs. Entities are referenced as "Type:ID"
+type Trace struct {
+	Root string `json:"root"`
+	// Candidates are the "action:dependency" pairs found through the lookup nodes
+	Candidates []string        `json:"candidates"`
+	Anchors    []string        `json:"anchors"`
+	Decisions  []TraceDecision `json:"decisions"`
+	// Jobs holds the ids of the created jobs, explaining never creates any
+	Jobs []int `json:"jobs"`
+}
+
+// TraceDecision is a single decision taken for an action dependency and anchor.
+// Input is the root of the input the decision was taken on, if any
+type TraceDecision struct {
+	Action     string `json:"action"`
+	Dependency string `json:"dependency"`
+	Anchor     string `json:"anchor"`
+	Step       string `json:"step"`
+	Passed     bool   `json:"passed"`
+	Input      string `json:"input,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// NewTrace returns an empty trace for the given batch root
+func NewTrace(root transport.TransportEntity) *Trace {
+	return &Trace{
+		Root:       traceLabel(root),
+		Candidates: make([]string, 0),
+		Anchors:    make([]string, 0),
+		Decisions:  make([]TraceDecision, 0),
+		Jobs:       make([]int, 0),
+	}
+}
+
+// DecisionsFor returns the decisions taken for the given action dependency
+func (t *Trace) DecisionsFor(action string, dependency string) []TraceDecision {
+	ret := make([]TraceDecision, 0)
+	for _, decision := range t.Decisions {
+		if decision.Action == action && decision.Dependency == dependency {
+			ret = append(ret, decision)
+		}
+	}
+	return ret
+}
+
+// Rejected returns the first failed decision of the given step for the action
+// dependency and true, or false if nothing has been rejected in that step
+func (t *Trace) Rejected(action string, dependency string, step string) (TraceDecision, bool) {
+	for _, decision := range t.DecisionsFor(action, dependency) {
+		if decision.Step == step && !decision.Passed {
+			return decision, true
+		}
+	}
+	return TraceDecision{}, false
+}
+
+// String renders the trace one decision per line for debugging output
+func (t *Trace) String() string {
+	var sb strings.Builder
+	sb.WriteString("root=" + t.Root + " candidates=[" + strings.Join(t.Candidates, " ") + "] anchors=[" + strings.Join(t.Anchors, " ") + "]\n")
+	for _, decision := range t.Decisions {
+		result := "pass"
+		if !decision.Passed {
+			result = "fail"
+		}
+		sb.WriteString(decision.Action + ":" + decision.Dependency + " anchor=" + decision.Anchor + " " + decision.Step + "=" + result)
+		if "" != decision.Input {
+			sb.WriteString(" input=" + decision.Input)
+		}
+		if "" != decision.Detail {
+			sb.WriteString(" " + decision.Detail)
+		}
+		sb.WriteString("\n")
+	}
+	jobs := make([]string, 0, len(t.Jobs))
+	for _, id := range t.Jobs {
+		jobs = append(jobs, strconv.Itoa(id))
+	}
+	sb.WriteString("jobs=[" + strings.Join(jobs, " ") + "]")
+	return sb.String()
+}
+
+// scope returns a recorder for the decisions of an action dependency and
+// anchor. Tracing is optional, a nil trace returns a nil scope
+func (t *Trace) scope(action string, dependency string, anchor transport.TransportEntity) *traceScope {
+	if nil == t {
+		return nil
+	}
+	return &traceScope{trace: t, action: action, dependency: dependency, anchor: traceLabel(anchor)}
+}
+
+// traceScope records the decisions of one action dependency and anchor.
+// All methods are safe to be called on nil, which records nothing
+type traceScope struct {
+	trace      *Trace
+	action     string
+	dependency string
+	anchor     string
+}
+
+func (ts *traceScope) record(step string, passed bool, input *transport.TransportEntity, detail string) {
+	if nil == ts {
+		return
+	}
+	decision := TraceDecision{Action: ts.action, Dependency: ts.dependency, Anchor: ts.anchor, Step: step, Passed: passed, Detail: detail}
+	if nil != input {
+		decision.Input = traceLabel(*input)
+	}
+	ts.trace.Decisions = append(ts.trace.Decisions, decision)
+}
+
+func (ts *traceScope) job(id int) {
+	if nil == ts {
+		return
+	}
+	ts.record(TRACE_STEP_JOB, true, nil, "id="+strconv.Itoa(id))
+	ts.trace.Jobs = append(ts.trace.Jobs, id)
+}
+
+func traceLabel(entity transport.TransportEntity) string {
+	return entity.Type + ":" + strconv.Itoa(entity.ID)
+}
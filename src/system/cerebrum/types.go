@@ -1,11 +1,16 @@
 package cerebrum
 
-import "github.com/voodooEntity/gits"
+import (
+	"github.com/voodooEntity/gits"
+	"github.com/voodooEntity/cyberbrain/src/system/util"
+)
 
 // Activity structure contains all the main components of the scheduler
 type Activity struct {
 	Demultiplexer *Demultiplexer
 	Scheduler     *Scheduler
+	RateLimiter   *RateLimiter
+	Timer         *Timer
 }
 
 // Consciousness structure contains all the main components of the cerebrum
@@ -20,6 +25,14 @@ type Consciousness struct {
 type Memory struct {
 	Gits   *gits.Gits
 	Mapper *Mapper
+	// Ident namespaces all system entities so multiple
+	// cyberbrains can share one gits instance
+	Ident string
+}
+
+// Scope returns the given system context namespaced by the memory ident
+func (m *Memory) Scope(context string) string {
+	return util.Scope(context, m.Ident)
 }
 
 // Compiled dependency pattern structures (read-only, cached)
@@ -29,8 +42,21 @@ type PatternNode struct {
 	Alias    string
 	Type     string
 	Mode     string
+	// AnyOf names the group of alternatives the node belongs to, if any
+	AnyOf    string
+	// Collection nodes keep all matching entities in one input
+	Collection bool
 	Filters  map[string][3]string // key -> [Field, Operator, Value]
+	// References are filters whose value is a field of another alias or type
+	References map[string][3]string // key -> [Field, Operator, Reference]
+	// Via lists the types of the intermediate entities a path node is reached
+	// through, between PathMin and PathMax of them
+	Via     []string
+	PathMin int
+	PathMax int
 	Children []*PatternNode
+	// Parents are matched upwards from the node
+	Parents  []*PatternNode
 	// NormalizedFilterFields contains derived keys for diagnostics, e.g.
 	// Properties.Transport -> Transport
 	NormalizedFilterFields map[string]string
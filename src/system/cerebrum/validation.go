@@ -0,0 +1,189 @@
+package cerebrum
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/voodooEntity/gits/src/transport"
+	"github.com/voodooEntity/cyberbrain/src/system/util"
+)
+
+// operators supported by filters, the same gits query matches support
+var filterOperators = []string{"==", "!=", "prefix", "suffix", "contain", ">", ">=", "<", "<=", "in"}
+
+// validateConfig checks an action config before it gets mapped, so broken
+// dependency structures are rejected at registration instead of silently
+// never matching during scheduling
+func (c Cortex) validateConfig(name string, config transport.TransportEntity) error {
+	if _, ok := c.register[name]; ok {
+		return errors.New("action '" + name + "' is already registered")
+	}
+	if "Action" != config.Type || name != config.Value {
+		return errors.New("action '" + name + "' config has to describe an action named '" + name + "', got " + config.Type + " '" + config.Value + "'")
+	}
+	categories, dependencies := 0, 0
+	for _, relation := range config.ChildRelations {
+		switch relation.Target.Type {
+		case "Category":
+			categories++
+		case "Dependency":
+			dependencies++
+		}
+	}
+	if 0 == categories || 0 == dependencies {
+		return errors.New("action '" + name + "' needs at least one category and one dependency")
+	}
+	for _, relation := range config.ChildRelations {
+		dependency := relation.Target
+		if "Dependency" != dependency.Type {
+			continue
+		}
+		if 1 != len(dependency.ChildRelations) {
+			return errors.New("dependency '" + dependency.Value + "' of action '" + name + "' needs exactly one root structure")
+		}
+		if err := c.validateDependency(dependency.ChildRelations[0].Target); nil != err {
+			return errors.New("dependency '" + dependency.Value + "' of action '" + name + "': " + err.Error())
+		}
+	}
+	return nil
+}
+
+// validateDependency checks the structure tree of a single dependency
+func (c Cortex) validateDependency(root transport.TransportEntity) error {
+	for _, property := range []string{"AnyOf", "Collection.Min", "Path.Via", "RelationContext"} {
+		if _, ok := root.Properties[property]; ok {
+			return errors.New("root structure '" + root.Value + "' can't have " + property + ", it isn't related to any node")
+		}
+	}
+	names := map[string]bool{}
+	primary := false
+	var nodes []transport.TransportEntity
+	var walk func(node transport.TransportEntity)
+	walk = func(node transport.TransportEntity) {
+		names[node.Value] = true
+		if alias := node.Properties["Alias"]; "" != alias {
+			names[alias] = true
+		}
+		if "Primary" == node.Properties["Type"] && "Absent" != node.Properties["Mode"] {
+			primary = true
+		}
+		nodes = append(nodes, node)
+		for _, related := range append(node.Children(), node.Parents()...) {
+			walk(related)
+		}
+	}
+	walk(root)
+	if !primary {
+		return errors.New("structure has no Primary node")
+	}
+	for _, node := range nodes {
+		if err := c.validateStructure(node, names); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateStructure checks a single structure node against the names (types
+// and aliases) known in its dependency
+func (c Cortex) validateStructure(node transport.TransportEntity, names map[string]bool) error {
+	if err := c.validateSiblingAliases(node.Value, node.Children()); nil != err {
+		return err
+	}
+	if err := c.validateSiblingAliases(node.Value, node.Parents()); nil != err {
+		return err
+	}
+	filters := 0
+	for _, prefix := range []string{"Filter", "RelationFilter", "Reference"} {
+		triples, present, err := c.filterTriples(node, prefix)
+		if nil != err {
+			return err
+		}
+		for filterName, triple := range triples {
+			// an empty value is a valid comparison, a missing one isn't
+			if 3 != present[filterName] || "" == triple[0] {
+				return errors.New(prefix + " '" + filterName + "' on '" + node.Value + "' is incomplete, it needs Field, Operator and Value")
+			}
+			if !util.StringInArray(filterOperators, triple[1]) {
+				return errors.New(prefix + " '" + filterName + "' on '" + node.Value + "' uses unknown operator '" + triple[1] + "'")
+			}
+			if "Reference" == prefix {
+				reference := strings.SplitN(triple[2], ".", 2)
+				if 2 != len(reference) || !names[reference[0]] {
+					return errors.New("Reference '" + filterName + "' on '" + node.Value + "' points to unknown alias '" + triple[2] + "'")
+				}
+			}
+		}
+		if "Filter" == prefix {
+			filters = len(triples)
+		}
+	}
+	if "Match" == node.Properties["Mode"] && 0 == filters {
+		return errors.New("structure '" + node.Value + "' is in Match mode without any filter")
+	}
+	// a related node linking back to the type of the node it hangs off would be
+	// matched against any entity of that type instead of the one it came from
+	for _, child := range node.Children() {
+		for _, back := range child.Parents() {
+			if back.Value == node.Value {
+				return errors.New("structure '" + child.Value + "' links back to its parent '" + node.Value + "', parent links can't point to the node they are attached under")
+			}
+		}
+	}
+	for _, parent := range node.Parents() {
+		for _, back := range parent.Children() {
+			if back.Value == node.Value {
+				return errors.New("parent structure '" + parent.Value + "' links back to its child '" + node.Value + "', child links can't point to the node they are attached above")
+			}
+		}
+	}
+	return nil
+}
+
+// validateSiblingAliases checks that no alias is used twice among the siblings
+// attached to the same node
+func (c Cortex) validateSiblingAliases(owner string, siblings []transport.TransportEntity) error {
+	seen := map[string]bool{}
+	for _, sibling := range siblings {
+		alias := sibling.Properties["Alias"]
+		if "" == alias {
+			continue
+		}
+		if seen[alias] {
+			return errors.New("duplicate alias '" + alias + "' among the structures attached to '" + owner + "'")
+		}
+		seen[alias] = true
+	}
+	return nil
+}
+
+// filterTriples collects the filters stored as <prefix>.<name>.(Field|Operator|Value)
+// on the node together with the amount of parts set per filter. Keys not
+// following that format are returned as error
+func (c Cortex) filterTriples(node transport.TransportEntity, prefix string) (map[string][3]string, map[string]int, error) {
+	triples := map[string][3]string{}
+	present := map[string]int{}
+	for key, value := range node.Properties {
+		if !strings.HasPrefix(key, prefix+".") {
+			continue
+		}
+		parts := strings.Split(key, ".")
+		if 3 != len(parts) || "" == parts[1] {
+			return nil, nil, errors.New("malformed filter key '" + key + "' on '" + node.Value + "'")
+		}
+		triple := triples[parts[1]]
+		switch parts[2] {
+		case "Field":
+			triple[0] = value
+		case "Operator":
+			triple[1] = value
+		case "Value":
+			triple[2] = value
+		default:
+			return nil, nil, errors.New("malformed filter key '" + key + "' on '" + node.Value + "'")
+		}
+		triples[parts[1]] = triple
+		present[parts[1]]++
+	}
+	return triples, present, nil
+}
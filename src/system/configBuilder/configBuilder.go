@@ -1,6 +1,12 @@
 package configBuilder
 
-import "github.com/voodooEntity/gits/src/transport"
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/voodooEntity/gits/src/transport"
+)
 
 type Priority string
 
@@ -12,19 +18,38 @@ const (
 type Mode string
 
 const (
-	MODE_SET   Mode = "Set"
-	MODE_MATCH Mode = "Match"
+	MODE_SET    Mode = "Set"
+	MODE_MATCH  Mode = "Match"
+	// MODE_ABSENT requires that the parent node has no such child. Filters
+	// and children of the node narrow down what must not exist
+	MODE_ABSENT Mode = "Absent"
+)
+
+// IdempotencyMode defines which part of an input decides if it has been
+// scheduled before. Without a mode the legacy signature is used, which
+// includes Versions, but inputs don't carry them so it acts like Identity
+type IdempotencyMode string
+
+const (
+	// IDEMPOTENCY_IDENTITY only considers Type and ID of the input entities
+	IDEMPOTENCY_IDENTITY IdempotencyMode = "Identity"
+	// IDEMPOTENCY_FIELDS considers Type and ID plus the selected fields
+	IDEMPOTENCY_FIELDS IdempotencyMode = "Fields"
+	// IDEMPOTENCY_CONTENT considers Type, ID, Value and all properties
+	IDEMPOTENCY_CONTENT IdempotencyMode = "Content"
 )
 
 type ConfigBuilder struct {
 	Dependencies map[string]*Structure
 	Name         string
 	Category     string
+	Properties   map[string]string
 }
 
 func NewConfig() *ConfigBuilder {
 	return &ConfigBuilder{
 		Dependencies: make(map[string]*Structure),
+		Properties:   make(map[string]string),
 	}
 }
 
@@ -43,13 +68,111 @@ func (builder *ConfigBuilder) AddDependency(name string, structure *Structure) *
 	return builder
 }
 
+// SetJobPriority sets the priority of the jobs created for this action.
+// Higher values are picked up first, default is 0
+func (builder *ConfigBuilder) SetJobPriority(priority int) *ConfigBuilder {
+	builder.Properties["Priority"] = strconv.Itoa(priority)
+	return builder
+}
+
+// SetConcurrency limits the amount of jobs of this action
+// executed at the same time, 0 means unlimited
+func (builder *ConfigBuilder) SetConcurrency(limit int) *ConfigBuilder {
+	builder.Properties["Concurrency"] = strconv.Itoa(limit)
+	return builder
+}
+
+// SetRateLimit allows at most limit job starts per period against the same
+// target. The target is the Value of the input entity with the given
+// dependency alias or type, the limit is shared with all actions using it
+func (builder *ConfigBuilder) SetRateLimit(aliasOrType string, limit int, per time.Duration) *ConfigBuilder {
+	builder.Properties["RateLimit.Key"] = aliasOrType
+	builder.Properties["RateLimit.Limit"] = strconv.Itoa(limit)
+	builder.Properties["RateLimit.Per"] = strconv.FormatInt(per.Milliseconds(), 10)
+	return builder
+}
+
+// SetDelay makes jobs of this action wait the given time after
+// their creation before they are picked up
+func (builder *ConfigBuilder) SetDelay(delay time.Duration) *ConfigBuilder {
+	builder.Properties["Delay"] = strconv.FormatInt(delay.Milliseconds(), 10)
+	return builder
+}
+
+// SetInterval re-runs the action against every input matching its
+// dependencies once per interval, in addition to the data driven jobs
+func (builder *ConfigBuilder) SetInterval(interval time.Duration) *ConfigBuilder {
+	builder.Properties["Interval"] = strconv.FormatInt(interval.Milliseconds(), 10)
+	return builder
+}
+
+// SetWitnessTTL lets the witness of a scheduled input expire after the given
+// time, so the same input is scheduled again once it has gone stale
+func (builder *ConfigBuilder) SetWitnessTTL(ttl time.Duration) *ConfigBuilder {
+	builder.Properties["WitnessTTL"] = strconv.FormatInt(ttl.Milliseconds(), 10)
+	return builder
+}
+
+// SetIdempotency selects when an input counts as already scheduled. fields
+// ("Value" or "Properties.<key>") are only used by IDEMPOTENCY_FIELDS
+func (builder *ConfigBuilder) SetIdempotency(mode IdempotencyMode, fields ...string) *ConfigBuilder {
+	builder.Properties["Idempotency.Mode"] = string(mode)
+	builder.Properties["Idempotency.Fields"] = strings.Join(fields, ",")
+	return builder
+}
+
+// SetIdempotencyKey keys the witness by the given expressions instead of the
+// whole input. Expressions are "<alias>.Value" or "<alias>.Properties.<key>",
+// the alias may also be a dependency type. E.g. "domain.Properties.Registrable"
+// runs the action once per registrable domain, no matter the subdomain
+func (builder *ConfigBuilder) SetIdempotencyKey(expressions ...string) *ConfigBuilder {
+	builder.Properties["Idempotency.Key"] = strings.Join(expressions, ",")
+	return builder
+}
+
+// SetTimeout limits the execution time of a single job. Actions implementing
+// ExecuteContext get their context cancelled. The timeout doesn't interrupt
+// actions only implementing Execute, they keep their neuron until Execute
+// returns and their results are dropped
+func (builder *ConfigBuilder) SetTimeout(timeout time.Duration) *ConfigBuilder {
+	builder.Properties["Timeout"] = strconv.FormatInt(timeout.Milliseconds(), 10)
+	return builder
+}
+
+// SetRetry enables retrying of failed jobs. A job is executed at most
+// maxAttempts times, the delay before the next attempt starts at backoff
+// and doubles with every attempt up to maxBackoff (0 = no limit)
+func (builder *ConfigBuilder) SetRetry(maxAttempts int, backoff time.Duration, maxBackoff time.Duration) *ConfigBuilder {
+	builder.Properties["Retry.MaxAttempts"] = strconv.Itoa(maxAttempts)
+	builder.Properties["Retry.Backoff"] = strconv.FormatInt(backoff.Milliseconds(), 10)
+	builder.Properties["Retry.MaxBackoff"] = strconv.FormatInt(maxBackoff.Milliseconds(), 10)
+	return builder
+}
+
+// AddRetryableError restricts retries to errors whose message contains
+// one of the added patterns. Without patterns every error is retried
+// unless it implements interfaces.RetryableErrorInterface
+func (builder *ConfigBuilder) AddRetryableError(pattern string) *ConfigBuilder {
+	patterns := make([]string, 0)
+	if val, ok := builder.Properties["Retry.Errors"]; ok {
+		patterns = strings.Split(val, "\n")
+	}
+	patterns = append(patterns, pattern)
+	builder.Properties["Retry.Errors"] = strings.Join(patterns, "\n")
+	return builder
+}
+
 func (builder *ConfigBuilder) Build() transport.TransportEntity {
+	properties := make(map[string]string)
+	for key, value := range builder.Properties {
+		properties[key] = value
+	}
 	configStructure := transport.TransportEntity{
 		ID:         -1,
 		Type:       "Action",
 		Value:      builder.Name,
 		Context:    "System",
-		Properties: make(map[string]string),
+		Properties: properties,
 		ChildRelations: []transport.TransportRelation{
 			{
 				Target: transport.TransportEntity{
@@ -91,16 +214,34 @@ type Structure struct {
     Filter   map[string][3]string
     Mode     Mode
     Alias    string
+    AnyOf    string
+    // Collection nodes hand all matching entities to one job, bounded by
+    // CollectionMin and CollectionMax (0 = unbounded)
+    Collection    bool
+    CollectionMin int
+    CollectionMax int
+    // RelationContext and RelationFilter constrain the relation leading to the node
+    RelationContext string
+    RelationFilter  map[string][3]string
+    // References compare a field to a field of another alias or type of the tree
+    References map[string][3]string
+    // PathVia, PathMin and PathMax make the node reachable through a bounded
+    // amount of intermediate entities of the via types
+    PathVia []string
+    PathMin int
+    PathMax int
 }
 
 func NewStructure(nodeType string) *Structure {
 	return &Structure{
-		Parents:  make([]*Structure, 0),
-		Children: make([]*Structure, 0),
-		Filter:   make(map[string][3]string),
-		Mode:     MODE_SET,
-		Priority: PRIORITY_SECONDARY,
-		Type:     nodeType,
+		Parents:        make([]*Structure, 0),
+		Children:       make([]*Structure, 0),
+		Filter:         make(map[string][3]string),
+		RelationFilter: make(map[string][3]string),
+		References:     make(map[string][3]string),
+		Mode:           MODE_SET,
+		Priority:       PRIORITY_SECONDARY,
+		Type:           nodeType,
 	}
 }
 
@@ -121,6 +262,17 @@ func (s *Structure) Transform() transport.TransportEntity {
         currEntity.Properties["Alias"] = s.Alias
     }
 
+    // alternative children of the same group share the group name
+    if s.AnyOf != "" {
+        currEntity.Properties["AnyOf"] = s.AnyOf
+    }
+
+    // collections keep their bounds, the presence of the min marks the node as collection
+    if s.Collection {
+        currEntity.Properties["Collection.Min"] = strconv.Itoa(s.CollectionMin)
+        currEntity.Properties["Collection.Max"] = strconv.Itoa(s.CollectionMax)
+    }
+
 	// add the filters
 	allFilters := ""
 	for key, value := range s.Filter {
@@ -130,6 +282,31 @@ func (s *Structure) Transform() transport.TransportEntity {
 		allFilters += value[0] + ","
 	}
 
+	// relation filters apply to the relation between the node and the one it's
+	// attached to, they are checked on the relation instead of the entity
+	if "" != s.RelationContext {
+		currEntity.Properties["RelationContext"] = s.RelationContext
+	}
+	for key, value := range s.RelationFilter {
+		currEntity.Properties["RelationFilter."+key+".Field"] = value[0]
+		currEntity.Properties["RelationFilter."+key+".Operator"] = value[1]
+		currEntity.Properties["RelationFilter."+key+".Value"] = value[2]
+	}
+
+	// paths are stored as comma separated via types and their hop bounds
+	if 0 < len(s.PathVia) {
+		currEntity.Properties["Path.Via"] = strings.Join(s.PathVia, ",")
+		currEntity.Properties["Path.Min"] = strconv.Itoa(s.PathMin)
+		currEntity.Properties["Path.Max"] = strconv.Itoa(s.PathMax)
+	}
+
+	// references are resolved against the input, they can't be part of the query
+	for key, value := range s.References {
+		currEntity.Properties["Reference."+key+".Field"] = value[0]
+		currEntity.Properties["Reference."+key+".Operator"] = value[1]
+		currEntity.Properties["Reference."+key+".Value"] = value[2]
+	}
+
 	// store a filter list for easier retrievel and as kinda index
 	if "" != allFilters {
 		// remove last char from allFilters
@@ -168,6 +345,29 @@ func (s *Structure) AddChild(child *Structure) *Structure {
 	return s
 }
 
+// AddAnyOf adds a group of alternative children. At least one of them has
+// to exist for the structure to match and every existing alternative results
+// in its own input. group names the alternatives and has to be unique per node
+func (s *Structure) AddAnyOf(group string, alternatives ...*Structure) *Structure {
+	for _, alternative := range alternatives {
+		alternative.AnyOf = group
+		s.Children = append(s.Children, alternative)
+	}
+	return s
+}
+
+// SetCollection marks the node as collection. Instead of one input per matching
+// entity a single input receives all of them, so the job is scheduled again when
+// the collection grows. The input is only scheduled if the amount of matching
+// entities is between min and max, a max of 0 means unbounded and a min of 0
+// makes the collection optional
+func (s *Structure) SetCollection(min int, max int) *Structure {
+	s.Collection = true
+	s.CollectionMin = min
+	s.CollectionMax = max
+	return s
+}
+
 func (s *Structure) SetPriority(priority Priority) *Structure {
 	s.Priority = priority
 	return s
@@ -183,6 +383,40 @@ func (s *Structure) AddFilter(name string, alpha string, operator string, beta s
     return s
 }
 
+// SetRelationContext requires the relation between this node and the node it
+// is attached to to have the given context, e.g. "resolvesTo"
+func (s *Structure) SetRelationContext(context string) *Structure {
+    s.RelationContext = context
+    return s
+}
+
+// AddRelationFilter adds a filter on the relation between this node and the node
+// it is attached to. alpha is either "Context" or "Properties.<key>"
+func (s *Structure) AddRelationFilter(name string, alpha string, operator string, beta string) *Structure {
+    s.RelationFilter[name] = [3]string{alpha, operator, beta}
+    return s
+}
+
+// AddReferenceFilter adds a filter comparing a field of this node to a field of
+// another node of the same dependency, e.g. AddReferenceFilter("port", "Value",
+// "==", "service.Properties.port"). reference is <alias>.Value, <alias>.Context
+// or <alias>.Properties.<key>, the type can be used instead of an alias
+func (s *Structure) AddReferenceFilter(name string, alpha string, operator string, reference string) *Structure {
+    s.References[name] = [3]string{alpha, operator, reference}
+    return s
+}
+
+// SetPath makes the node reachable from the node it is attached to through min
+// to max intermediate entities of the via types instead of a direct relation,
+// e.g. a URL under a Domain at any depth of Subdomains up to 5:
+// NewStructure("URL").SetPath(0, 5, "Subdomain")
+func (s *Structure) SetPath(min int, max int, via ...string) *Structure {
+    s.PathVia = via
+    s.PathMin = min
+    s.PathMax = max
+    return s
+}
+
 // SetAlias assigns a stable alias/name to this dependency node. This enables
 // distinguishing multiple siblings of the same Type at the same level.
 func (s *Structure) SetAlias(alias string) *Structure {
@@ -12,3 +12,9 @@ type ActionInterface interface {
 type LoggerInterface interface {
 	Println(v ...interface{})
 }
+
+// RetryableErrorInterface can be implemented by errors returned from an
+// actions Execute to decide on their own if the job should be retried
+type RetryableErrorInterface interface {
+	Retryable() bool
+}
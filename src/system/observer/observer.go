@@ -27,10 +27,13 @@ type Tracker struct {
 	Version int
 }
 
+// New creates an observer for the given memory. runnerAmount is the amount
+// of neurons the cyberbrain has been started with, since the pool can be
+// scaled at runtime the endgame is checked against the living neurons
 func New(memoryInstance *cerebrum.Memory, runnerAmount int, cb func(memoryInstance *cerebrum.Memory), logger *archivist.Archivist, lethal bool) *Observer {
 	logger.Info("Creating observer")
 	var runners []Tracker
-	qry := query.New().Read("Neuron")
+	qry := query.New().Read("Neuron").Match("Context", "==", memoryInstance.Scope("Cyberbrain"))
 	res := memoryInstance.Gits.Query().Execute(qry)
 	for _, val := range res.Entities {
 		runners = append(runners, Tracker{
@@ -83,25 +86,38 @@ func (o *Observer) Loop() {
 func (o *Observer) ReachedEndgame() bool {
     // If the system has been terminated externally (or by a timeout tick),
     // we should stop the observer loop immediately to avoid hanging forever.
-    if !util.IsAlive(o.memory.Gits) {
+    if !util.IsAlive(o.memory.Gits, o.memory.Ident) {
         return true
     }
-    runnerQry := query.New().Read("Neuron").Match("Properties.State", "==", "Searching")
+    runnerQry := query.New().Read("Neuron").Match("Context", "==", o.memory.Scope("Cyberbrain")).Match("Properties.State", "==", "Searching")
     sysRunners := o.memory.Gits.Query().Execute(runnerQry)
     o.log.Debug(archivist.DEBUG_LEVEL_MAX, "Observer: searching neurons", sysRunners.Amount)
-	o.log.Debug(archivist.DEBUG_LEVEL_MAX, "Observer: total amount created neurons", o.runnerAmount)
-	openJobs := cerebrum.GetOpenJobs(o.memory.Gits)
-	if openJobs.Amount == 0 && sysRunners.Amount == o.runnerAmount {
+	// the neuron pool can be scaled at runtime, so we always
+	// compare against the currently living neurons
+	liveAmount := o.LiveNeuronAmount()
+	o.log.Debug(archivist.DEBUG_LEVEL_MAX, "Observer: amount of living neurons", liveAmount, "started with", o.runnerAmount)
+	openJobs := cerebrum.GetOpenJobs(o.memory)
+	if openJobs.Amount == 0 && sysRunners.Amount == liveAmount {
 		changedVersion := false
 		for _, sysRunner := range sysRunners.Entities {
+			tracked := false
 			for tid, tracker := range o.Runners {
 				if sysRunner.ID == tracker.ID {
+					tracked = true
 					if sysRunner.Version != tracker.Version {
 						changedVersion = true
 						o.Runners[tid].Version = sysRunner.Version
 					}
 				}
 			}
+			// neurons spawned after the observer has been created
+			if !tracked {
+				changedVersion = true
+				o.Runners = append(o.Runners, Tracker{
+					ID:      sysRunner.ID,
+					Version: sysRunner.Version,
+				})
+			}
 		}
 		if changedVersion {
 			o.InactiveIncrement = 0
@@ -121,7 +137,7 @@ func (o *Observer) Endgame() {
 	o.log.Info("executing endgame")
 	// if we are lethal we gonne stop cyberbrain
 	if o.lethal {
-		util.Terminate(o.memory.Gits)
+		util.Terminate(o.memory.Gits, o.memory.Ident)
 		for !o.AllNeuronDead() {
 			time.Sleep(10 * time.Millisecond)
 		}
@@ -131,10 +147,15 @@ func (o *Observer) Endgame() {
 }
 
 func (o *Observer) AllNeuronDead() bool {
-	qry := query.New().Read("Neuron").Match("Properties.State", "==", "Dead")
-	runners := o.memory.Gits.Query().Execute(qry)
-	if runners.Amount == o.runnerAmount {
+	if 0 == o.LiveNeuronAmount() {
 		return true
 	}
 	return false
 }
+
+// LiveNeuronAmount returns the amount of neurons that are not Dead
+func (o *Observer) LiveNeuronAmount() int {
+	qry := query.New().Read("Neuron").Match("Context", "==", o.memory.Scope("Cyberbrain")).Match("Properties.State", "!=", "Dead")
+	runners := o.memory.Gits.Query().Execute(qry)
+	return runners.Amount
+}
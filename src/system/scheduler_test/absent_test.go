@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+    "testing"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionResolve — Domain that has no IP child yet
+type actionResolve struct{}
+
+func (a *actionResolve) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionResolve) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionResolve").SetCategory("Test")
+	dep := cfgb.NewStructure("Domain").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+		cfgb.NewStructure("IP").SetMode(cfgb.MODE_ABSENT),
+	)
+	cfg.AddDependency("domain", dep)
+	return cfg.Build()
+}
+
+func newActionResolve() interfaces.ActionInterface { return &actionResolve{} }
+
+// actionSSHScan — Host without a Service of type ssh
+type actionSSHScan struct{}
+
+func (a *actionSSHScan) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionSSHScan) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionSSHScan").SetCategory("Test")
+	dep := cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+		cfgb.NewStructure("Service").SetMode(cfgb.MODE_ABSENT).AddFilter("ssh", "Value", "==", "ssh"),
+	)
+	cfg.AddDependency("host", dep)
+	return cfg.Build()
+}
+
+func newActionSSHScan() interfaces.ActionInterface { return &actionSSHScan{} }
+
+// Test AB.1 — Only entities without the absent child match
+func Test_Absent_Child_PreventsMatch(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionResolve}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "bare.example"}, "Data"), cortex)
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "resolved.example",
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "IP", Value: "10.0.0.1"}}},
+    }, "Data"), cortex)
+
+    jobs := mem.Gits.Query().Execute(gits.NewQuery().Read("Job"))
+    if jobs.Amount != 1 {
+        t.Fatalf("expected a job only for the domain without IP, got %d", jobs.Amount)
+    }
+}
+
+// Test AB.2 — The missing node appearing later revokes the open job and its witness
+func Test_Absent_NodeAppears_RevokesOpenJob(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionResolve}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    domain := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "late.example"}, "Data")
+    sched.Run(domain, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected 1 job, got %d", amount)
+    }
+
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", ID: domain.ID,
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "IP", Value: "10.0.0.2"}}},
+    }, "Data"), cortex)
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected the open job to be revoked, got %d", amount)
+    }
+    if witnesses := mem.Gits.Query().Execute(gits.NewQuery().Read("Memory")); witnesses.Amount != 0 {
+        t.Fatalf("expected the witness of the revoked job to be deleted, got %d", witnesses.Amount)
+    }
+    if inputs := mem.Gits.Query().Execute(gits.NewQuery().Read("Input")); inputs.Amount != 0 {
+        t.Fatalf("expected the input of the revoked job to be deleted, got %d", inputs.Amount)
+    }
+}
+
+// Test AB.3 — Filters narrow down which child must be absent
+func Test_Absent_Filter_OnlyMatchingChildPrevents(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionSSHScan}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Host", Value: "web-1",
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "Service", Value: "http"}}},
+    }, "Data"), cortex)
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Host", Value: "jump-1",
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "Service", Value: "ssh"}}},
+    }, "Data"), cortex)
+
+    jobs := mem.Gits.Query().Execute(gits.NewQuery().Read("Job").To(gits.NewQuery().Read("Input")))
+    if jobs.Amount != 1 {
+        t.Fatalf("expected a job only for the host without ssh, got %d", jobs.Amount)
+    }
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+    "testing"
+
+    "github.com/voodooEntity/gits/src/transport"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionAnyAddress — Host with either an IPv4 or an IPv6 child
+type actionAnyAddress struct{}
+
+func (a *actionAnyAddress) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionAnyAddress) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionAnyAddress").SetCategory("Test")
+	dep := cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddAnyOf("address",
+		cfgb.NewStructure("IPv4"),
+		cfgb.NewStructure("IPv6"),
+	)
+	cfg.AddDependency("hostAddress", dep)
+	return cfg.Build()
+}
+
+func newActionAnyAddress() interfaces.ActionInterface { return &actionAnyAddress{} }
+
+func hostWithAddresses(value string, addresses map[string][]string) transport.TransportEntity {
+    host := transport.TransportEntity{Type: "Host", Value: value}
+    for _, typ := range []string{"IPv4", "IPv6"} {
+        for _, address := range addresses[typ] {
+            host.ChildRelations = append(host.ChildRelations, transport.TransportRelation{Target: transport.TransportEntity{Type: typ, Value: address}})
+        }
+    }
+    return host
+}
+
+// Test AO.1 — One input per satisfied branch, none without a branch
+func Test_AnyOf_OneInputPerBranch(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionAnyAddress}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    sched.Run(mem.Mapper.MapTransportDataWithContext(hostWithAddresses("v4-only", map[string][]string{"IPv4": {"10.0.0.1"}}), "Data"), cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected 1 job for the IPv4 branch, got %d", amount)
+    }
+    sched.Run(mem.Mapper.MapTransportDataWithContext(hostWithAddresses("dual", map[string][]string{"IPv4": {"10.0.0.2"}, "IPv6": {"fe80::2"}}), "Data"), cortex)
+    if amount := countJobs(mem); amount != 3 {
+        t.Fatalf("expected one job per branch of the dual stack host (3 overall), got %d", amount)
+    }
+    sched.Run(mem.Mapper.MapTransportDataWithContext(hostWithAddresses("none", nil), "Data"), cortex)
+    if amount := countJobs(mem); amount != 3 {
+        t.Fatalf("expected no job for a host without address, got %d", amount)
+    }
+}
+
+// Test AO.2 — Branches are deduplicated independently, a new branch schedules on its own
+func Test_AnyOf_WitnessPerBranch(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionAnyAddress}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    host := mem.Mapper.MapTransportDataWithContext(hostWithAddresses("later", map[string][]string{"IPv4": {"10.0.1.1", "10.0.1.2"}}), "Data")
+    sched.Run(host, cortex)
+    if amount := countJobs(mem); amount != 2 {
+        t.Fatalf("expected 2 jobs for the IPv4 addresses, got %d", amount)
+    }
+
+    update := hostWithAddresses("", map[string][]string{"IPv6": {"fe80::1"}})
+    update.ID = host.ID
+    sched.Run(mem.Mapper.MapTransportDataWithContext(update, "Data"), cortex)
+    if amount := countJobs(mem); amount != 3 {
+        t.Fatalf("expected the IPv6 branch to add exactly one job, got %d", amount)
+    }
+
+    sched.Run(host, cortex)
+    if amount := countJobs(mem); amount != 3 {
+        t.Fatalf("expected known branches to be deduplicated, got %d", amount)
+    }
+}
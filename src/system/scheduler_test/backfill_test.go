@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+    "strconv"
+    "testing"
+
+    "github.com/voodooEntity/gits/src/transport"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionBackfillNested — Alpha -> Beta(primary) structure resolved per batch
+type actionBackfillNested struct{}
+
+func (a *actionBackfillNested) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionBackfillNested) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionBackfillNested").SetCategory("Test")
+	dep := cfgb.NewStructure("Alpha").SetMode(cfgb.MODE_SET).AddChild(
+		cfgb.NewStructure("Beta").SetPriority(cfgb.PRIORITY_PRIMARY).SetMode(cfgb.MODE_SET),
+	)
+	cfg.AddDependency("alphaBeta", dep)
+	return cfg.Build()
+}
+
+func newActionBackfillNested() interfaces.ActionInterface { return &actionBackfillNested{} }
+
+// Test BF.1 — Backfill schedules existing matches once and respects the root filters
+func Test_Backfill_ExistingData_OncePerInput(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionIdem}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    // data learned without scheduling, as if stored before the action existed
+    for i := 0; i < 5; i++ {
+        mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "bf-" + strconv.Itoa(i), Properties: map[string]string{"Port": "1", "Note": "a"}}, "Data")
+    }
+    mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "bf-skip", Properties: map[string]string{"Port": "1", "Note": "skip"}}, "Data")
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected no jobs before the backfill, got %d", amount)
+    }
+
+    act, _ := cortex.GetAction("ActionIdem")
+    if created, err := sched.Backfill(act, "alpha", 2); err != nil || created != 5 {
+        t.Fatalf("expected backfill to create 5 jobs, got %d (%v)", created, err)
+    }
+    if created, err := sched.Backfill(act, "", 2); err != nil || created != 0 {
+        t.Fatalf("expected witnesses to prevent a second backfill, got %d (%v)", created, err)
+    }
+    if _, err := sched.Backfill(act, "missing", 2); err == nil {
+        t.Fatalf("expected an error for an unknown dependency")
+    }
+}
+
+// Test BF.2 — Nested structures are resolved per batch and each batch only loads its roots
+func Test_Backfill_NestedStructure_Batched(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionBackfillNested}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    for i := 0; i < 3; i++ {
+        mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "bf-root-" + strconv.Itoa(i),
+            ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "Beta", Value: "bf-leaf-" + strconv.Itoa(i)}}},
+        }, "Data")
+    }
+    // an Alpha without Beta doesn't match
+    mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "bf-lonely"}, "Data")
+
+    act, _ := cortex.GetAction("ActionBackfillNested")
+    if created, err := sched.Backfill(act, "alphaBeta", 1); err != nil || created != 3 {
+        t.Fatalf("expected backfill to create 3 jobs, got %d (%v)", created, err)
+    }
+    if amount := countJobs(mem); amount != 3 {
+        t.Fatalf("expected 3 jobs overall, got %d", amount)
+    }
+}
+
+// Test BF.3 — Roots are paged by ID past filtered entities and internal contexts are skipped
+func Test_Backfill_Paging_SkipsFilteredAndInternal(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionIdem}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    // not matching entities interleaved with matching ones across several batches
+    for i := 0; i < 4; i++ {
+        mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "bf-page-" + strconv.Itoa(i), Properties: map[string]string{"Port": "1", "Note": "a"}}, "Data")
+        mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "bf-filtered-" + strconv.Itoa(i), Properties: map[string]string{"Port": "0", "Note": "a"}}, "Data")
+    }
+    // matching data in the cyberbrain's own context is no input
+    mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "bf-internal", Properties: map[string]string{"Port": "1", "Note": "a"}}, mem.Scope("System"))
+
+    act, _ := cortex.GetAction("ActionIdem")
+    if created, err := sched.Backfill(act, "alpha", 1); err != nil || created != 4 {
+        t.Fatalf("expected backfill to create 4 jobs, got %d (%v)", created, err)
+    }
+    if amount := countJobs(mem); amount != 4 {
+        t.Fatalf("expected 4 jobs overall, got %d", amount)
+    }
+}
@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+    "encoding/json"
+    "testing"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    "github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionPortSummary — Host [Primary] -> all Ports [Primary, Collection]
+type actionPortSummary struct{}
+
+func (a *actionPortSummary) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionPortSummary) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionPortSummary").SetCategory("Test")
+	dep := cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+		cfgb.NewStructure("Port").SetPriority(cfgb.PRIORITY_PRIMARY).SetCollection(1, 0),
+	)
+	cfg.AddDependency("hostPorts", dep)
+	return cfg.Build()
+}
+
+func newActionPortSummary() interfaces.ActionInterface { return &actionPortSummary{} }
+
+// actionPortPair — Host [Primary] -> 2 to 3 Ports [Primary, Collection]
+type actionPortPair struct{}
+
+func (a *actionPortPair) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionPortPair) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionPortPair").SetCategory("Test")
+	dep := cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+		cfgb.NewStructure("Port").SetPriority(cfgb.PRIORITY_PRIMARY).SetCollection(2, 3),
+	)
+	cfg.AddDependency("hostPortPair", dep)
+	return cfg.Build()
+}
+
+func newActionPortPair() interfaces.ActionInterface { return &actionPortPair{} }
+
+func hostWithPorts(id int, value string, ports ...string) transport.TransportEntity {
+    host := transport.TransportEntity{Type: "Host", ID: id, Value: value}
+    for _, port := range ports {
+        host.ChildRelations = append(host.ChildRelations, transport.TransportRelation{Target: transport.TransportEntity{Type: "Port", Value: port}})
+    }
+    return host
+}
+
+// collectionSizes returns the amount of Port children of every job input
+func collectionSizes(t *testing.T, mem *cerebrum.Memory) []int {
+    var sizes []int
+    inputs := mem.Gits.Query().Execute(gits.NewQuery().Read("Input"))
+    for _, entity := range inputs.Entities {
+        var input transport.TransportEntity
+        if err := json.Unmarshal([]byte(entity.Properties["Data"]), &input); err != nil {
+            t.Fatalf("could not decode job input: %s", err)
+        }
+        sizes = append(sizes, len(input.ChildRelations))
+    }
+    return sizes
+}
+
+// Test C.1 — All Ports of a Host end up in a single job, a growing collection schedules again
+func Test_Collection_SingleJobAndGrowth(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionPortSummary}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    host := mem.Mapper.MapTransportDataWithContext(hostWithPorts(0, "collector", "22", "80", "443"), "Data")
+    sched.Run(host, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected 1 job for the whole collection, got %d", amount)
+    }
+    if sizes := collectionSizes(t, mem); len(sizes) != 1 || sizes[0] != 3 {
+        t.Fatalf("expected the job input to carry all 3 Ports, got %v", sizes)
+    }
+
+    sched.Run(host, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected an unchanged collection to be deduplicated, got %d", amount)
+    }
+
+    sched.Run(mem.Mapper.MapTransportDataWithContext(hostWithPorts(host.ID, "", "8080"), "Data"), cortex)
+    if amount := countJobs(mem); amount != 2 {
+        t.Fatalf("expected the grown collection to schedule again, got %d", amount)
+    }
+    sizes := collectionSizes(t, mem)
+    if len(sizes) != 2 || (sizes[0] != 4 && sizes[1] != 4) {
+        t.Fatalf("expected the new job input to carry all 4 Ports, got %v", sizes)
+    }
+}
+
+// Test C.2 — Collections outside of their bounds are not scheduled
+func Test_Collection_Bounds(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionPortPair}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    host := mem.Mapper.MapTransportDataWithContext(hostWithPorts(0, "bounded", "22"), "Data")
+    sched.Run(host, cortex)
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected no job below the min of the collection, got %d", amount)
+    }
+
+    sched.Run(mem.Mapper.MapTransportDataWithContext(hostWithPorts(host.ID, "", "80"), "Data"), cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected 1 job once the min is reached, got %d", amount)
+    }
+
+    sched.Run(mem.Mapper.MapTransportDataWithContext(hostWithPorts(host.ID, "", "443", "8443"), "Data"), cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected no job above the max of the collection, got %d", amount)
+    }
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+    "testing"
+
+    "github.com/voodooEntity/gits/src/transport"
+    "github.com/voodooEntity/cyberbrain/src/system/archivist"
+    "github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionLimited — at most one job of this action may run at the same time
+type actionLimited struct{}
+
+func (a *actionLimited) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionLimited) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionLimited").SetCategory("Scan").SetConcurrency(1)
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionLimited() interfaces.ActionInterface { return &actionLimited{} }
+
+// actionScanBeta — unlimited action sharing the category of actionLimited
+type actionScanBeta struct{}
+
+func (a *actionScanBeta) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionScanBeta) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionScanBeta").SetCategory("Scan")
+	cfg.AddDependency("beta", cfgb.NewStructure("Beta").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionScanBeta() interfaces.ActionInterface { return &actionScanBeta{} }
+
+// Test C.1 — A second neuron can't pick up a job of an action running at its limit
+func Test_Concurrency_ActionLimit_BlocksSecondNeuron(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionLimited}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+    logger := archivist.New(&archivist.Config{})
+
+    for _, value := range []string{"lim-1", "lim-2"} {
+        mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: value, Properties: map[string]string{}}, "Data")
+        sched.Run(mapped, cortex)
+    }
+    if open := cerebrum.GetOpenJobs(mem); open.Amount != 1 || len(open.Entities[0].Parents()) != 2 {
+        t.Fatalf("expected two open jobs, got %+v", open)
+    }
+
+    first := cerebrum.NewNeuron(1, cortex, mem, nil, logger)
+    second := cerebrum.NewNeuron(2, cortex, mem, nil, logger)
+    if !first.FindJob() {
+        t.Fatalf("expected first neuron to get a job")
+    }
+    if second.FindJob() {
+        t.Fatalf("expected second neuron to be blocked by the action limit")
+    }
+    if byAction, byCategory := cerebrum.CountAssignedJobs(mem); byAction["ActionLimited"] != 1 || byCategory["Scan"] != 1 {
+        t.Fatalf("expected one assigned job, got %v %v", byAction, byCategory)
+    }
+}
+
+// Test C.2 — Category limits apply across actions and are enforced on assignment
+func Test_Concurrency_CategoryLimit_AcrossActions(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionLimited, newActionScanBeta}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+    logger := archivist.New(&archivist.Config{})
+
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "cat-a", Properties: map[string]string{}}, "Data"), cortex)
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Beta", Value: "cat-b", Properties: map[string]string{}}, "Data"), cortex)
+
+    first := cerebrum.NewNeuron(1, cortex, mem, nil, logger)
+    second := cerebrum.NewNeuron(2, cortex, mem, nil, logger)
+    first.SetCategoryLimits(map[string]int{"Scan": 1})
+    second.SetCategoryLimits(map[string]int{"Scan": 1})
+    if !first.FindJob() {
+        t.Fatalf("expected first neuron to get a job")
+    }
+    if second.FindJob() {
+        t.Fatalf("expected second neuron to be blocked by the category limit")
+    }
+
+    // the check under the storage lock holds even if the pre check is skipped
+    open := cerebrum.GetOpenJobs(mem)
+    if open.Amount != 1 || len(open.Entities[0].Parents()) != 1 {
+        t.Fatalf("expected one job left open, got %+v", open)
+    }
+    job := cerebrum.Load(open.Entities[0].Parents()[0].ID, mem, logger)
+    if job.SetLimits(cerebrum.ConcurrencyLimits{Category: 1}).AssignToRunner(2) {
+        t.Fatalf("expected assignment to be refused at the category limit")
+    }
+}
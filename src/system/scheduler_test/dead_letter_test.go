@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+    "errors"
+    "testing"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    "github.com/voodooEntity/cyberbrain"
+    "github.com/voodooEntity/cyberbrain/src/system/archivist"
+    "github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// newDeadLetteredBrain returns a stopped cyberbrain with a failed job per value
+func newDeadLetteredBrain(t *testing.T, values ...string) (*cyberbrain.Cyberbrain, *cerebrum.Memory) {
+    cb, mem := newBrain(t, nil, 1, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{
+        "ActionFailing": newActionFailing(errors.New("permanent failure"), 0),
+    })
+    cb.Start()
+    for _, value := range values {
+        cb.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: value})
+    }
+    waitFor(t, "the jobs to be dead lettered", func() bool {
+        return len(cb.ListFailedJobs()) == len(values)
+    })
+    cb.Stop()
+    return cb, mem
+}
+
+// Test DL.1 — Failed jobs are listed and inspected with their error and input
+func Test_DeadLetter_ListAndInspect(t *testing.T) {
+    cb, _ := newDeadLetteredBrain(t, "dl-1", "dl-2")
+
+    failed := cb.ListFailedJobs()
+    if len(failed) != 2 {
+        t.Fatalf("expected two failed jobs, got %+v", failed)
+    }
+    job, err := cb.InspectFailedJob(failed[0].ID)
+    if nil != err {
+        t.Fatalf("expected the failed job to be inspected, got %v", err)
+    }
+    if job.Action != "ActionFailing" || job.Attempt != 1 || job.Input.Type != "Alpha" || job.Witness == "" || job.Failed.IsZero() {
+        t.Fatalf("expected the failed job with its details, got %+v", job)
+    }
+    if _, err := cb.InspectFailedJob(0); nil == err {
+        t.Fatalf("expected an unknown job not to be inspected")
+    }
+}
+
+// Test DL.2 — Requeued jobs are reopened, a job sharing the witness of an active one only with allowDuplicate
+func Test_DeadLetter_Requeue(t *testing.T) {
+    cb, mem := newDeadLetteredBrain(t, "dl-1", "dl-2")
+    failed := cb.ListFailedJobs()
+
+    if err := cb.RequeueFailedJob(failed[0].ID, false); nil != err {
+        t.Fatalf("expected the job to be requeued, got %v", err)
+    }
+    open := cerebrum.GetJobIDsByState(mem, "Open")
+    if len(open) != 1 || open[0] != failed[0].ID {
+        t.Fatalf("expected job %d to be open, got %v", failed[0].ID, open)
+    }
+    if properties := jobProperties(mem, failed[0].ID); properties["Attempt"] != "0" || properties["LastError"] == "" {
+        t.Fatalf("expected the attempts to be reset and the error kept, got %v", properties)
+    }
+    if err := cb.RequeueFailedJob(failed[0].ID, false); nil == err {
+        t.Fatalf("expected a job not in the dead letter queue to be refused")
+    }
+
+    // let the second job share the witness of the open one
+    cerebrum.Load(failed[1].ID, mem, archivist.New(&archivist.Config{})).SetProperty("Witness", failed[0].Witness)
+    if err := cb.RequeueFailedJob(failed[1].ID, false); nil == err {
+        t.Fatalf("expected the requeue to be refused while an active job has the same witness")
+    }
+    if err := cb.RequeueFailedJob(failed[1].ID, true); nil != err {
+        t.Fatalf("expected allowDuplicate to requeue the job, got %v", err)
+    }
+    if open := cerebrum.GetJobIDsByState(mem, "Open"); len(open) != 2 {
+        t.Fatalf("expected both jobs to be open, got %v", open)
+    }
+}
+
+// Test DL.3 — Purging checks all ids first, an unknown one leaves the queue untouched
+func Test_DeadLetter_Purge(t *testing.T) {
+    cb, mem := newDeadLetteredBrain(t, "dl-1", "dl-2", "dl-3")
+    failed := cb.ListFailedJobs()
+
+    if purged, err := cb.PurgeFailedJobs(failed[0].ID, 0); nil == err || purged != 0 {
+        t.Fatalf("expected the purge to be refused without purging, got %d %v", purged, err)
+    }
+    if len(cb.ListFailedJobs()) != 3 {
+        t.Fatalf("expected no job to be purged by a refused purge")
+    }
+    if purged, err := cb.PurgeFailedJobs(failed[0].ID); nil != err || purged != 1 {
+        t.Fatalf("expected one job to be purged, got %d %v", purged, err)
+    }
+    if purged, err := cb.PurgeFailedJobs(); nil != err || purged != 2 {
+        t.Fatalf("expected the remaining jobs to be purged, got %d %v", purged, err)
+    }
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected no job to be left, got %d", amount)
+    }
+    if inputs := mem.Gits.Query().Execute(gits.NewQuery().Read("Input")); inputs.Amount != 0 {
+        t.Fatalf("expected the inputs to be purged with their jobs, got %d", inputs.Amount)
+    }
+}
+
+// Test DL.4 — A job deleted while running is skipped when finishing instead of failing the neuron
+func Test_DeadLetter_DeletedWhileRunning(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionFailing(errors.New("permanent failure"), 0)}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+    for _, value := range []string{"dl-gone-1", "dl-gone-2"} {
+        sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: value, Properties: map[string]string{}}, "Data"), cortex)
+    }
+
+    neuron := cerebrum.NewNeuron(1, cortex, mem, nil, archivist.New(&archivist.Config{}))
+    for _, finish := range []func(){
+        func() { neuron.FinishJobError(errors.New("permanent failure")) },
+        func() { neuron.FinishJobSuccess(nil) },
+    } {
+        if !neuron.FindJob() {
+            t.Fatalf("expected the neuron to get a job")
+        }
+        for _, id := range cerebrum.GetRunningJobIDs(mem) {
+            cerebrum.Load(id, mem, archivist.New(&archivist.Config{})).Delete()
+        }
+        finish()
+    }
+    if failed := cerebrum.GetJobIDsByState(mem, "Failed"); len(failed) != 0 {
+        t.Fatalf("expected no deleted job to be dead lettered, got %v", failed)
+    }
+}
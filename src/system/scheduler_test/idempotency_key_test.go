@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+    "testing"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionWhois — runs once per registrable domain, keyed by an alias expression
+type actionWhois struct{}
+
+func (a *actionWhois) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionWhois) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionWhois").SetCategory("Test").SetIdempotencyKey("domain.Properties.Registrable")
+	cfg.AddDependency("domain", cfgb.NewStructure("Domain").SetAlias("domain").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionWhois() interfaces.ActionInterface { return &actionWhois{} }
+
+// actionKeyByType — keyed by the Value of the parent Alpha, addressed by type
+type actionKeyByType struct{}
+
+func (a *actionKeyByType) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionKeyByType) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionKeyByType").SetCategory("Test").SetIdempotencyKey("Alpha.Value")
+	dep := cfgb.NewStructure("Alpha").SetMode(cfgb.MODE_SET).AddChild(
+		cfgb.NewStructure("Beta").SetPriority(cfgb.PRIORITY_PRIMARY).SetMode(cfgb.MODE_SET),
+	)
+	cfg.AddDependency("alphaBeta", dep)
+	return cfg.Build()
+}
+
+func newActionKeyByType() interfaces.ActionInterface { return &actionKeyByType{} }
+
+// Test K.1 — Subdomains of the same registrable domain share one witness
+func Test_IdempotencyKey_AliasExpression_OncePerKey(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionWhois}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    domains := [][2]string{
+        {"a.example.com", "example.com"},
+        {"b.example.com", "example.com"},
+        {"www.other.org", "other.org"},
+    }
+    for _, domain := range domains {
+        mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: domain[0], Properties: map[string]string{"Registrable": domain[1]}}, "Data")
+        sched.Run(mapped, cortex)
+    }
+
+    if jobs := mem.Gits.Query().Execute(gits.NewQuery().Read("Job")); jobs.Amount != 2 {
+        t.Fatalf("expected one job per registrable domain (2), got %d", jobs.Amount)
+    }
+}
+
+// Test K.2 — A type name works as key target and structural changes below it don't re-trigger
+func Test_IdempotencyKey_TypeExpression_IgnoresOtherEntities(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionKeyByType}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    alpha := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "key-a"}, "Data")
+    for _, beta := range []string{"key-b1", "key-b2"} {
+        mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", ID: alpha.ID,
+            ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "Beta", Value: beta}}},
+        }, "Data")
+        sched.Run(mapped, cortex)
+    }
+
+    if jobs := mem.Gits.Query().Execute(gits.NewQuery().Read("Job")); jobs.Amount != 1 {
+        t.Fatalf("expected a single job keyed by Alpha.Value, got %d", jobs.Amount)
+    }
+}
@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+    "testing"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+    "github.com/voodooEntity/cyberbrain/src/system/util"
+)
+
+// actionIdem — MATCH on Alpha so updates of Port and Note are relevant,
+// the idempotency mode is set per test via idemMode/idemFields
+type actionIdem struct{}
+
+var idemMode cfgb.IdempotencyMode
+var idemFields []string
+
+func (a *actionIdem) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionIdem) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionIdem").SetCategory("Test")
+	if "" != idemMode {
+		cfg.SetIdempotency(idemMode, idemFields...)
+	}
+	dep := cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY).SetMode(cfgb.MODE_MATCH).
+		AddFilter("port", "Properties.Port", ">", "0").
+		AddFilter("note", "Properties.Note", "!=", "skip")
+	cfg.AddDependency("alpha", dep)
+	return cfg.Build()
+}
+
+func newActionIdem() interfaces.ActionInterface { return &actionIdem{} }
+
+// actionIdemSet — SET on Alpha without filters, updates are only relevant
+// through the idempotency mode
+type actionIdemSet struct{}
+
+func (a *actionIdemSet) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionIdemSet) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionIdemSet").SetCategory("Test")
+	if "" != idemMode {
+		cfg.SetIdempotency(idemMode, idemFields...)
+	}
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionIdemSet() interfaces.ActionInterface { return &actionIdemSet{} }
+
+// runIdemSequence creates an Alpha and applies the given property updates,
+// scheduling after every step. Returns the amount of jobs after each step.
+func runIdemSequence(t *testing.T, mode cfgb.IdempotencyMode, fields []string, value string, updates []map[string]string) []int {
+    return runIdemSequenceWith(t, newActionIdem, mode, fields, value, updates)
+}
+
+// runIdemSequenceWith works like runIdemSequence for the given action
+func runIdemSequenceWith(t *testing.T, action func() interfaces.ActionInterface, mode cfgb.IdempotencyMode, fields []string, value string, updates []map[string]string) []int {
+    idemMode, idemFields = mode, fields
+    defer func() { idemMode, idemFields = "", nil }()
+    actions := []func() interfaces.ActionInterface{action}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    var amounts []int
+    mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: value, Properties: map[string]string{"Port": "1", "Note": "a"}}, "Data")
+    sched.Run(mapped, cortex)
+    amounts = append(amounts, mem.Gits.Query().Execute(gits.NewQuery().Read("Job")).Amount)
+    for _, properties := range updates {
+        mapped = mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", ID: mapped.ID, Properties: properties}, "Data")
+        sched.Run(mapped, cortex)
+        amounts = append(amounts, mem.Gits.Query().Execute(gits.NewQuery().Read("Job")).Amount)
+    }
+    return amounts
+}
+
+func expectAmounts(t *testing.T, got []int, want []int) {
+    t.Helper()
+    if len(got) != len(want) {
+        t.Fatalf("expected job amounts %v, got %v", want, got)
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Fatalf("expected job amounts %v, got %v", want, got)
+        }
+    }
+}
+
+var idemUpdates = []map[string]string{
+    {"Port": "2"}, // relevant field of the Fields mode
+    {"Note": "b"}, // other property
+    {"Port": "1"}, // back to a former Port
+    {"Note": "a"}, // back to the initial content
+}
+
+// Test I.1 — Default: inputs don't carry Versions, so property updates don't re-trigger
+func Test_Idempotency_Default_ActsLikeIdentity(t *testing.T) {
+    expectAmounts(t, runIdemSequence(t, "", nil, "idem-v", idemUpdates), []int{1, 1, 1, 1, 1})
+}
+
+// Test I.2 — Identity: updates of a known input never re-trigger
+func Test_Idempotency_Identity_IgnoresUpdates(t *testing.T) {
+    expectAmounts(t, runIdemSequence(t, cfgb.IDEMPOTENCY_IDENTITY, nil, "idem-i", idemUpdates), []int{1, 1, 1, 1, 1})
+}
+
+// Test I.3 — Fields: only changes of the selected fields to unseen values re-trigger
+func Test_Idempotency_Fields_SelectedKeysOnly(t *testing.T) {
+    expectAmounts(t, runIdemSequence(t, cfgb.IDEMPOTENCY_FIELDS, []string{"Properties.Port"}, "idem-f", idemUpdates), []int{1, 2, 2, 2, 2})
+}
+
+// Test I.4 — Content: any change to unseen content re-triggers, known content doesn't
+func Test_Idempotency_Content_FullHash(t *testing.T) {
+    // 1/a -> 2/a -> 2/b -> 1/b -> 1/a(known)
+    expectAmounts(t, runIdemSequence(t, cfgb.IDEMPOTENCY_CONTENT, nil, "idem-c", idemUpdates), []int{1, 2, 3, 4, 4})
+}
+
+// Test I.4b — Content and Fields re-trigger on updates of SET dependencies without any filter
+func Test_Idempotency_SetMode_UpdatesRelevantByMode(t *testing.T) {
+    expectAmounts(t, runIdemSequenceWith(t, newActionIdemSet, cfgb.IDEMPOTENCY_CONTENT, nil, "idem-sc", idemUpdates), []int{1, 2, 3, 4, 4})
+    expectAmounts(t, runIdemSequenceWith(t, newActionIdemSet, cfgb.IDEMPOTENCY_FIELDS, []string{"Properties.Port"}, "idem-sf", idemUpdates), []int{1, 2, 2, 2, 2})
+    expectAmounts(t, runIdemSequenceWith(t, newActionIdemSet, cfgb.IDEMPOTENCY_IDENTITY, nil, "idem-si", idemUpdates), []int{1, 1, 1, 1, 1})
+}
+
+// Test I.5 — Signatures are deterministic and independent of Version and relation order
+func Test_Idempotency_Signatures_Deterministic(t *testing.T) {
+    child1 := transport.TransportEntity{Type: "Beta", ID: 1, Value: "b1", Version: 1}
+    child2 := transport.TransportEntity{Type: "Beta", ID: 2, Value: "b2", Version: 4}
+    a := transport.TransportEntity{Type: "Alpha", ID: 7, Value: "x", Version: 1, Properties: map[string]string{"Port": "1", "bMap": ""},
+        ChildRelations: []transport.TransportRelation{{Target: child1}, {Target: child2}}}
+    b := transport.TransportEntity{Type: "Alpha", ID: 7, Value: "x", Version: 9, Properties: map[string]string{"Port": "1"},
+        ChildRelations: []transport.TransportRelation{{Target: child2}, {Target: child1}}}
+
+    if util.GenerateIdentitySignature(a) != util.GenerateIdentitySignature(b) {
+        t.Fatalf("expected identity signatures to match")
+    }
+    if util.GenerateFieldSignature(a, []string{"Value", "Properties.Port"}) != util.GenerateFieldSignature(b, []string{"Properties.Port", "Value"}) {
+        t.Fatalf("expected field signatures to match")
+    }
+    if util.GenerateContentSignature(a) != util.GenerateContentSignature(b) {
+        t.Fatalf("expected content signatures to match")
+    }
+    b.Properties["Port"] = "2"
+    if util.GenerateContentSignature(a) == util.GenerateContentSignature(b) {
+        t.Fatalf("expected content signatures to differ after a property change")
+    }
+}
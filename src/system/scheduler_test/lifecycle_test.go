@@ -0,0 +1,542 @@
+package scheduler
+
+import (
+    "context"
+    "errors"
+    "log"
+    "os"
+    "strconv"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    "github.com/voodooEntity/cyberbrain"
+    "github.com/voodooEntity/cyberbrain/src/system/archivist"
+    "github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// blocker — shared state of the actionBlocking instances of a test
+type blocker struct {
+	started chan string
+	release chan struct{}
+	timeout time.Duration
+}
+
+func newBlocker() *blocker {
+	return &blocker{started: make(chan string, 10), release: make(chan struct{})}
+}
+
+// actionBlocking — v2 action blocking until released or its context is done
+type actionBlocking struct {
+	b *blocker
+}
+
+func (a *actionBlocking) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionBlocking) ExecuteContext(ctx context.Context, input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	a.b.started <- jobID
+	select {
+	case <-a.b.release:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (a *actionBlocking) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionBlocking").SetCategory("Test")
+	if 0 < a.b.timeout {
+		cfg.SetTimeout(a.b.timeout)
+	}
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionBlocking(b *blocker) func() interfaces.ActionInterface {
+	return func() interfaces.ActionInterface { return &actionBlocking{b: b} }
+}
+
+// actionSlow — v1 action taking longer than its timeout
+type actionSlow struct {
+	finished *int32
+}
+
+func (a *actionSlow) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	time.Sleep(200 * time.Millisecond)
+	atomic.StoreInt32(a.finished, 1)
+	return []transport.TransportEntity{{Type: "Beta", Value: "dropped"}}, nil
+}
+
+func (a *actionSlow) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionSlow").SetCategory("Test").SetTimeout(20 * time.Millisecond)
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+// actionStuck — v1 action blocking until released, no matter its timeout
+type actionStuck struct {
+	b *blocker
+}
+
+func (a *actionStuck) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	a.b.started <- jobID
+	<-a.b.release
+	return nil, nil
+}
+
+func (a *actionStuck) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionStuck").SetCategory("Test")
+	if 0 < a.b.timeout {
+		cfg.SetTimeout(a.b.timeout)
+	}
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionStuck(b *blocker) func() interfaces.ActionInterface {
+	return func() interfaces.ActionInterface { return &actionStuck{b: b} }
+}
+
+// newBrain creates a cyberbrain on the given gits instance, a new one if nil,
+// with the actions registered. The returned memory shares its storage
+func newBrain(t *testing.T, gitsInstance *gits.Gits, neurons int, settings cyberbrain.Settings, actions map[string]func() interfaces.ActionInterface) (*cyberbrain.Cyberbrain, *cerebrum.Memory) {
+	if nil == gitsInstance {
+		gitsInstance = gits.NewInstance(GenerateRandomString(10))
+	}
+	settings.Gits = gitsInstance
+	settings.Ident = "lifecycle"
+	settings.NeuronAmount = neurons
+	settings.Logger = log.New(os.Stdout, "", 0)
+	cb := cyberbrain.New(settings)
+	for name, action := range actions {
+		if err := cb.RegisterAction(name, action); nil != err {
+			t.Fatalf("expected %s to register, got %v", name, err)
+		}
+	}
+	return cb, &cerebrum.Memory{Gits: gitsInstance, Ident: "lifecycle"}
+}
+
+// waitStarted waits until a blocking job has been started
+func waitStarted(t *testing.T, b *blocker) {
+	select {
+	case <-b.started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a blocking job to be started")
+	}
+}
+
+// waitFor polls cond until it is true or fails the test after 5s
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Test LC.1 — A retired neuron stays reachable until it is dead, so its job can still be cancelled
+func Test_Lifecycle_ScaleDown_RetiringJobCancellable(t *testing.T) {
+    b := newBlocker()
+    cb, mem := newBrain(t, nil, 1, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{"ActionBlocking": newActionBlocking(b)})
+    if err := cb.Start(); nil != err {
+        t.Fatalf("expected start to succeed, got %v", err)
+    }
+    defer cb.Stop()
+
+    cb.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "retiring"})
+    waitStarted(t, b)
+    running := cerebrum.GetRunningJobIDs(mem)
+    if len(running) != 1 {
+        t.Fatalf("expected one running job, got %v", running)
+    }
+
+    if err := cb.ScaleNeurons(0); nil != err {
+        t.Fatalf("expected scaling down to succeed, got %v", err)
+    }
+    if err := cb.CancelJob(running[0]); nil != err {
+        t.Fatalf("expected the job of the retiring neuron to be cancellable, got %v", err)
+    }
+    waitFor(t, "the cancelled job to be dead lettered", func() bool {
+        return len(cb.ListFailedJobs()) == 1
+    })
+    if failed := cb.ListFailedJobs()[0]; !failed.Cancelled {
+        t.Fatalf("expected the job to be marked cancelled, got %+v", failed)
+    }
+    waitFor(t, "the retired neuron to be forgotten", func() bool {
+        return nil != cb.CancelJob(running[0])
+    })
+}
+
+// Test LC.2 — A v2 action exceeding its timeout is interrupted and the timeout is recorded as cause
+func Test_Lifecycle_Timeout_RecordsCause(t *testing.T) {
+    b := newBlocker()
+    b.timeout = 50 * time.Millisecond
+    cb, _ := newBrain(t, nil, 1, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{"ActionBlocking": newActionBlocking(b)})
+    cb.Start()
+    defer cb.Stop()
+
+    cb.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "timeout"})
+    waitStarted(t, b)
+    waitFor(t, "the timed out job to be dead lettered", func() bool {
+        return len(cb.ListFailedJobs()) == 1
+    })
+    failed := cb.ListFailedJobs()[0]
+    if failed.Cancelled || failed.CancelCause != cerebrum.ErrJobTimeout.Error() || !strings.Contains(failed.Error, cerebrum.ErrJobTimeout.Error()) {
+        t.Fatalf("expected a job failed by its timeout, got %+v", failed)
+    }
+}
+
+// Test LC.3 — A cancelled job is interrupted, dead lettered and marked cancelled
+func Test_Lifecycle_CancelJob_RecordsCause(t *testing.T) {
+    b := newBlocker()
+    cb, mem := newBrain(t, nil, 1, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{"ActionBlocking": newActionBlocking(b)})
+    cb.Start()
+    defer cb.Stop()
+
+    cb.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "cancel"})
+    waitStarted(t, b)
+    id := cerebrum.GetRunningJobIDs(mem)[0]
+    if err := cb.CancelJob(id); nil != err {
+        t.Fatalf("expected the running job to be cancelled, got %v", err)
+    }
+    waitFor(t, "the cancelled job to be dead lettered", func() bool {
+        return len(cb.ListFailedJobs()) == 1
+    })
+    failed := cb.ListFailedJobs()[0]
+    if failed.ID != id || !failed.Cancelled || failed.CancelCause != cerebrum.ErrJobCancelled.Error() {
+        t.Fatalf("expected job %d to be cancelled, got %+v", id, failed)
+    }
+    if err := cb.CancelJob(id); nil == err {
+        t.Fatalf("expected a finished job not to be cancellable")
+    }
+}
+
+// Test LC.4 — Running jobs are bound to the start context, once it is done they are aborted and reopened
+func Test_Lifecycle_StartContext_AbortsRunningJob(t *testing.T) {
+    b := newBlocker()
+    cb, mem := newBrain(t, nil, 1, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{"ActionBlocking": newActionBlocking(b)})
+    ctx, cancel := context.WithCancel(context.Background())
+    cb.StartContext(ctx)
+    defer cb.Stop()
+
+    cb.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "abort"})
+    waitStarted(t, b)
+    cancel()
+    waitFor(t, "the aborted job to be reopened", func() bool {
+        return cerebrum.GetOpenJobs(mem).Amount == 1
+    })
+    job := cerebrum.GetOpenJobs(mem).Entities[0].Parents()[0]
+    if job.Properties["CancelReason"] != cerebrum.ErrJobAborted.Error() || job.Properties["Attempt"] != "" {
+        t.Fatalf("expected the job to be reopened as aborted without an attempt, got %v", job.Properties)
+    }
+}
+
+// Test LC.5 — A v1 action is run to its end, its results are dropped once it timed out
+func Test_Lifecycle_Timeout_V1RunsToEnd(t *testing.T) {
+    var finished int32
+    cb, mem := newBrain(t, nil, 1, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{
+        "ActionSlow": func() interfaces.ActionInterface { return &actionSlow{finished: &finished} },
+    })
+    cb.Start()
+    defer cb.Stop()
+
+    cb.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "slow"})
+    waitFor(t, "the timed out job to be dead lettered", func() bool {
+        return len(cb.ListFailedJobs()) == 1
+    })
+    if 1 != atomic.LoadInt32(&finished) {
+        t.Fatalf("expected the v1 action to have finished before its job failed")
+    }
+    if failed := cb.ListFailedJobs()[0]; failed.CancelCause != cerebrum.ErrJobTimeout.Error() {
+        t.Fatalf("expected the timeout as cause, got %+v", failed)
+    }
+    if dropped := mem.Gits.Query().Execute(gits.NewQuery().Read("Beta")); dropped.Amount != 0 {
+        t.Fatalf("expected the results of the timed out action to be dropped, got %d", dropped.Amount)
+    }
+}
+
+// Test LC.6 — Shutdown waits for the running job to finish
+func Test_Lifecycle_Shutdown_Drains(t *testing.T) {
+    b := newBlocker()
+    cb, mem := newBrain(t, nil, 1, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{"ActionBlocking": newActionBlocking(b)})
+    cb.Start()
+
+    cb.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "drain"})
+    waitStarted(t, b)
+    done := make(chan error, 1)
+    go func() {
+        done <- cb.Shutdown(context.Background())
+    }()
+    select {
+    case err := <-done:
+        t.Fatalf("expected shutdown to wait for the running job, returned %v", err)
+    case <-time.After(100 * time.Millisecond):
+    }
+
+    close(b.release)
+    select {
+    case err := <-done:
+        if nil != err {
+            t.Fatalf("expected a clean shutdown, got %v", err)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatalf("expected shutdown to return once the job finished")
+    }
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected the drained job to be finished, got %d jobs", amount)
+    }
+}
+
+// Test LC.7 — Shutdown timing out aborts the running job, which is reopened once its action returned
+func Test_Lifecycle_Shutdown_TimeoutAborts(t *testing.T) {
+    b := newBlocker()
+    cb, mem := newBrain(t, nil, 1, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{"ActionBlocking": newActionBlocking(b)})
+    cb.Start()
+
+    cb.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "timeout"})
+    waitStarted(t, b)
+    id := cerebrum.GetRunningJobIDs(mem)[0]
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+
+    err := cb.Shutdown(ctx)
+    var aborted *cyberbrain.AbortedJobsError
+    if !errors.As(err, &aborted) || len(aborted.Jobs) != 1 || aborted.Jobs[0] != id {
+        t.Fatalf("expected job %d to be reported aborted, got %v", id, err)
+    }
+    waitFor(t, "the aborted job to be reopened", func() bool {
+        return len(cerebrum.GetRunningJobIDs(mem)) == 0
+    })
+    open := cerebrum.GetOpenJobs(mem)
+    if open.Amount != 1 || open.Entities[0].Parents()[0].Properties["CancelReason"] != cerebrum.ErrJobAborted.Error() {
+        t.Fatalf("expected the aborted job to be reopened, got %+v", open)
+    }
+}
+
+// Test LC.8 — A stopped cyberbrain can be restarted without running the interrupted job twice
+func Test_Lifecycle_StopRestart_NoDoubleExecution(t *testing.T) {
+    b := newBlocker()
+    cb, mem := newBrain(t, nil, 2, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{"ActionBlocking": newActionBlocking(b)})
+    cb.Start()
+
+    cb.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "restart"})
+    waitStarted(t, b)
+    if err := cb.Stop(); nil != err {
+        t.Fatalf("expected stop to succeed, got %v", err)
+    }
+    if running := cerebrum.GetRunningJobIDs(mem); len(running) != 0 {
+        t.Fatalf("expected no job to be running after stop, got %v", running)
+    }
+
+    if err := cb.Start(); nil != err {
+        t.Fatalf("expected restart to succeed, got %v", err)
+    }
+    defer cb.Stop()
+    waitStarted(t, b)
+    select {
+    case <-b.started:
+        t.Fatalf("expected the interrupted job to be executed only once after restart")
+    case <-time.After(200 * time.Millisecond):
+    }
+    close(b.release)
+    waitFor(t, "the restarted job to finish", func() bool {
+        return countJobs(mem) == 0
+    })
+}
+
+// seedCrashedRun leaves the state of a run that didn't shut down cleanly in
+// the gits instance: a stale alive marker, a neuron that isn't alive anymore
+// working on the first job and the second job Assigned without any neuron
+func seedCrashedRun(t *testing.T, gitsInstance *gits.Gits) []int {
+    b := newBlocker()
+    crashed, mem := newBrain(t, gitsInstance, 1, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{"ActionBlocking": newActionBlocking(b)})
+    ctx, cancel := context.WithCancel(context.Background())
+    crashed.StartContext(ctx)
+    crashed.ScaleNeurons(0)
+    waitFor(t, "the neuron to retire", func() bool {
+        return gitsInstance.Query().Execute(gits.NewQuery().Read("Neuron").Match("Properties.State", "==", "Dead")).Amount == 1
+    })
+    crashed.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "crashed-1"})
+    crashed.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "crashed-2"})
+    // stop its timer without terminating, like a killed process would
+    cancel()
+
+    ids := cerebrum.GetJobIDsByState(mem, "Open")
+    if len(ids) != 2 {
+        t.Fatalf("expected two open jobs to be seeded, got %v", ids)
+    }
+    logger := archivist.New(&archivist.Config{})
+    gitsInstance.MapData(transport.TransportEntity{
+        Type:       "Neuron",
+        Value:      "99",
+        Context:    mem.Scope("Cyberbrain"),
+        Properties: map[string]string{"State": "Working"},
+    })
+    for _, id := range ids {
+        if !cerebrum.Load(id, mem, logger).AssignToRunner(99) {
+            t.Fatalf("expected job %d to be assigned", id)
+        }
+    }
+    gitsInstance.Query().Execute(gits.NewQuery().Unlink("Neuron").Match("Value", "==", "99").To(
+        gits.NewQuery().Find("Job").Match("ID", "==", strconv.Itoa(ids[1])),
+    ))
+    if running := cerebrum.GetRunningJobIDs(mem); len(running) != 1 {
+        t.Fatalf("expected the first job to be linked to the neuron, got %v", running)
+    }
+    return ids
+}
+
+// Test LC.9 — Jobs of a crashed run are reopened and executed, its neurons are removed
+func Test_Lifecycle_Recovery_Reopen(t *testing.T) {
+    gitsInstance := gits.NewInstance(GenerateRandomString(10))
+    seedCrashedRun(t, gitsInstance)
+
+    b := newBlocker()
+    close(b.release)
+    cb, mem := newBrain(t, gitsInstance, 1, cyberbrain.Settings{Recovery: cyberbrain.RECOVERY_REOPEN}, map[string]func() interfaces.ActionInterface{"ActionBlocking": newActionBlocking(b)})
+    if err := cb.Start(); nil != err {
+        t.Fatalf("expected start to recover from the stale alive marker, got %v", err)
+    }
+    defer cb.Stop()
+
+    if stale := mem.Gits.Query().Execute(gits.NewQuery().Read("Neuron").Match("Value", "==", "99")); stale.Amount != 0 {
+        t.Fatalf("expected the neuron of the crashed run to be removed, got %d", stale.Amount)
+    }
+    waitStarted(t, b)
+    waitStarted(t, b)
+    waitFor(t, "the recovered jobs to finish", func() bool {
+        return countJobs(mem) == 0
+    })
+    if executed := len(b.started); executed != 0 {
+        t.Fatalf("expected each recovered job to run once, got %d more executions", executed)
+    }
+}
+
+// Test LC.10 — Jobs of a crashed run are dropped with RECOVERY_FAIL
+func Test_Lifecycle_Recovery_Fail(t *testing.T) {
+    gitsInstance := gits.NewInstance(GenerateRandomString(10))
+    seedCrashedRun(t, gitsInstance)
+
+    b := newBlocker()
+    cb, mem := newBrain(t, gitsInstance, 1, cyberbrain.Settings{Recovery: cyberbrain.RECOVERY_FAIL}, map[string]func() interfaces.ActionInterface{"ActionBlocking": newActionBlocking(b)})
+    cb.Start()
+    defer cb.Stop()
+
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected the orphaned jobs to be dropped, got %d", amount)
+    }
+    if len(cb.ListFailedJobs()) != 0 || len(b.started) != 0 {
+        t.Fatalf("expected the dropped jobs neither to be dead lettered nor executed")
+    }
+}
+
+// Test LC.11 — Jobs of a crashed run are dead lettered with RECOVERY_DEAD_LETTER
+func Test_Lifecycle_Recovery_DeadLetter(t *testing.T) {
+    gitsInstance := gits.NewInstance(GenerateRandomString(10))
+    ids := seedCrashedRun(t, gitsInstance)
+
+    b := newBlocker()
+    cb, _ := newBrain(t, gitsInstance, 1, cyberbrain.Settings{Recovery: cyberbrain.RECOVERY_DEAD_LETTER}, map[string]func() interfaces.ActionInterface{"ActionBlocking": newActionBlocking(b)})
+    cb.Start()
+    defer cb.Stop()
+
+    failed := cb.ListFailedJobs()
+    if len(failed) != 2 {
+        t.Fatalf("expected both orphaned jobs to be dead lettered, got %+v", failed)
+    }
+    for i, job := range failed {
+        if job.ID != ids[i] || job.Error != "job orphaned by unclean shutdown" || job.Attempt != 1 || job.Input.Type != "Alpha" {
+            t.Fatalf("expected job %d to be dead lettered with its input, got %+v", ids[i], job)
+        }
+    }
+    if len(b.started) != 0 {
+        t.Fatalf("expected the dead lettered jobs not to be executed")
+    }
+}
+
+// Test LC.12 — Scaling is safe concurrent to reading the neuron amount and the started state
+func Test_Lifecycle_ScaleNeurons_ConcurrentToReaders(t *testing.T) {
+    cb, _ := newBrain(t, nil, 1, cyberbrain.Settings{}, nil)
+    if err := cb.Start(); nil != err {
+        t.Fatalf("expected start to succeed, got %v", err)
+    }
+    defer cb.Stop()
+
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        for i := 0; i < 16; i++ {
+            cb.ScaleNeurons(i % 3)
+        }
+    }()
+    for i := 0; i < 16; i++ {
+        cb.GetObserverInstance(func(memoryInstance *cerebrum.Memory) {}, false)
+        if err := cb.RegisterAction("ActionBlocking", newActionBlocking(newBlocker())); nil == err {
+            t.Fatalf("expected registering on a running cyberbrain to fail")
+        }
+    }
+    <-done
+}
+
+// Test LC.13 — The timeout doesn't interrupt a v1 action, its job keeps the neuron until Execute returns
+func Test_Lifecycle_Timeout_V1HoldsNeuron(t *testing.T) {
+    b := newBlocker()
+    b.timeout = 20 * time.Millisecond
+    cb, mem := newBrain(t, nil, 1, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{"ActionStuck": newActionStuck(b)})
+    cb.Start()
+    defer cb.Stop()
+
+    cb.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "stuck"})
+    waitStarted(t, b)
+    time.Sleep(10 * b.timeout)
+    if running := cerebrum.GetRunningJobIDs(mem); len(running) != 1 || len(cb.ListFailedJobs()) != 0 {
+        t.Fatalf("expected the job to still be running past its timeout, got %v running", running)
+    }
+
+    close(b.release)
+    waitFor(t, "the timed out job to be dead lettered", func() bool {
+        return len(cb.ListFailedJobs()) == 1
+    })
+    if failed := cb.ListFailedJobs()[0]; failed.CancelCause != cerebrum.ErrJobTimeout.Error() {
+        t.Fatalf("expected the timeout as cause, got %+v", failed)
+    }
+}
+
+// Test LC.14 — Shutdown returns by its deadline even if a v1 action ignores it
+func Test_Lifecycle_Shutdown_TimeoutDoesntWaitForV1(t *testing.T) {
+    b := newBlocker()
+    cb, mem := newBrain(t, nil, 1, cyberbrain.Settings{}, map[string]func() interfaces.ActionInterface{"ActionStuck": newActionStuck(b)})
+    cb.Start()
+
+    cb.LearnAndSchedule(transport.TransportEntity{Type: "Alpha", Value: "stuck"})
+    waitStarted(t, b)
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+
+    returned := make(chan error)
+    go func() { returned <- cb.Shutdown(ctx) }()
+    select {
+    case err := <-returned:
+        var aborted *cyberbrain.AbortedJobsError
+        if !errors.As(err, &aborted) || len(aborted.Jobs) != 1 {
+            t.Fatalf("expected the stuck job to be reported aborted, got %v", err)
+        }
+    case <-time.After(time.Second):
+        close(b.release)
+        t.Fatalf("expected shutdown to return by its deadline")
+    }
+
+    close(b.release)
+    waitFor(t, "the stuck job to be reopened once it returned", func() bool {
+        return cerebrum.GetOpenJobs(mem).Amount == 1
+    })
+}
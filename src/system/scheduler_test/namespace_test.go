@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+    "testing"
+
+    "github.com/voodooEntity/gits/src/transport"
+    "github.com/voodooEntity/cyberbrain/src/system/archivist"
+    "github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+    "github.com/voodooEntity/cyberbrain/src/system/util"
+)
+
+// Test N.1 — Two cyberbrains sharing one gits instance keep their jobs, lookups and witnesses apart
+func Test_Namespace_SharedGits_SeparatesJobsAndWitnesses(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionE}
+    schedA, memA, cortexA := setupFreshAndSeed(nil, actions)
+
+    // second brain on the very same graph with its own ident
+    logger := archivist.New(&archivist.Config{})
+    memB := &cerebrum.Memory{
+        Gits:   memA.Gits,
+        Mapper: cerebrum.NewMapper(memA.Gits, logger),
+        Ident:  "second",
+    }
+    createBaseData(memB)
+    cortexB := prepareCortex(memB, logger, actions)
+    schedB := getScheduler(memB, logger)
+
+    if !util.IsAlive(memA.Gits, memA.Ident) || !util.IsAlive(memB.Gits, memB.Ident) {
+        t.Fatalf("expected both brains to be alive")
+    }
+
+    // learn once, schedule with brain B only
+    mapped := memA.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "a-ns1"}, "Data")
+    schedB.Run(mapped, cortexB)
+
+    if open := cerebrum.GetOpenJobs(memA); open.Amount != 0 {
+        t.Fatalf("expected no open jobs for brain A, got %d", len(open.Entities[0].Parents()))
+    }
+    openB := cerebrum.GetOpenJobs(memB)
+    if openB.Amount != 1 || len(openB.Entities[0].Parents()) != 1 {
+        t.Fatalf("expected exactly one open job for brain B, got %+v", openB)
+    }
+
+    // brain A must not be blocked by the witness of brain B
+    schedA.Run(mapped, cortexA)
+    openA := cerebrum.GetOpenJobs(memA)
+    if openA.Amount != 1 || len(openA.Entities[0].Parents()) != 1 {
+        t.Fatalf("expected exactly one open job for brain A, got %+v", openA)
+    }
+
+    // terminating one brain leaves the other alive
+    util.Terminate(memB.Gits, memB.Ident)
+    if !util.IsAlive(memA.Gits, memA.Ident) || util.IsAlive(memB.Gits, memB.Ident) {
+        t.Fatalf("expected only brain B to be terminated")
+    }
+}
@@ -0,0 +1,177 @@
+package scheduler
+
+import (
+    "testing"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionReverse — IP [Primary] <- Domain [Secondary], the pattern points upwards from its root
+type actionReverse struct{}
+
+func (a *actionReverse) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+    return nil, nil
+}
+
+func (a *actionReverse) GetConfig() transport.TransportEntity {
+    cfg := cfgb.NewConfig().SetName("ActionReverse").SetCategory("Test")
+    dep := cfgb.NewStructure("IP").SetPriority(cfgb.PRIORITY_PRIMARY).AddParent(
+        cfgb.NewStructure("Domain").SetPriority(cfgb.PRIORITY_SECONDARY),
+    )
+    cfg.AddDependency("ipDomain", dep)
+    return cfg.Build()
+}
+
+func newActionReverse() interfaces.ActionInterface { return &actionReverse{} }
+
+// actionMixed — Domain <- IP [Primary] -> Port, parents and children in one pattern
+type actionMixed struct{}
+
+func (a *actionMixed) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+    return nil, nil
+}
+
+func (a *actionMixed) GetConfig() transport.TransportEntity {
+    cfg := cfgb.NewConfig().SetName("ActionMixed").SetCategory("Test")
+    dep := cfgb.NewStructure("IP").SetPriority(cfgb.PRIORITY_PRIMARY).AddParent(
+        cfgb.NewStructure("Domain"),
+    ).AddChild(
+        cfgb.NewStructure("Port").SetPriority(cfgb.PRIORITY_PRIMARY),
+    )
+    cfg.AddDependency("domainIPPort", dep)
+    return cfg.Build()
+}
+
+func newActionMixed() interfaces.ActionInterface { return &actionMixed{} }
+
+// actionOrphan — IP without a parent Domain
+type actionOrphan struct{}
+
+func (a *actionOrphan) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+    return nil, nil
+}
+
+func (a *actionOrphan) GetConfig() transport.TransportEntity {
+    cfg := cfgb.NewConfig().SetName("ActionOrphan").SetCategory("Test")
+    dep := cfgb.NewStructure("IP").SetPriority(cfgb.PRIORITY_PRIMARY).AddParent(
+        cfgb.NewStructure("Domain").SetMode(cfgb.MODE_ABSENT),
+    )
+    cfg.AddDependency("orphanIP", dep)
+    return cfg.Build()
+}
+
+func newActionOrphan() interfaces.ActionInterface { return &actionOrphan{} }
+
+// Test P.1 — A Domain with a new IP child matches the upward pattern once, rooted at the IP
+func Test_Parent_NewChild_TriggersOnce(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionReverse}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "up.example",
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "IP", Value: "10.1.0.1"}}},
+    }, "Data")
+    sched.Run(mapped, cortex)
+
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected exactly 1 job for IP <- Domain, got %d", amount)
+    }
+    pattern := sched.DebugGetCompiledPattern(cortex, "ActionReverse", "ipDomain")
+    if pattern == nil || pattern.Type != "IP" || len(pattern.Parents) != 1 || pattern.Parents[0].Type != "Domain" {
+        t.Fatalf("expected compiled pattern IP <- Domain, got %+v", pattern)
+    }
+}
+
+// Test P.2 — An IP shared by two Domains results in one input per parent
+func Test_Parent_MultipleParents_Demultiplexed(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionReverse}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    first := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "a.shared",
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "IP", Value: "10.1.0.2"}}},
+    }, "Data")
+    sched.Run(first, cortex)
+    second := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "b.shared",
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "IP", ID: -2, Value: "10.1.0.2"}}},
+    }, "Data")
+    sched.Run(second, cortex)
+
+    if amount := countJobs(mem); amount != 2 {
+        t.Fatalf("expected one job per parent Domain (2), got %d", amount)
+    }
+}
+
+// Test P.3 — Data learned in parent form and relation-only deltas trigger the upward pattern
+func Test_Parent_ParentFormAndRelationOnly(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionReverse}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "IP", Value: "10.1.0.3",
+        ParentRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "Domain", Value: "parent.form"}}},
+    }, "Data")
+    sched.Run(mapped, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected 1 job for data learned in parent form, got %d", amount)
+    }
+
+    domain := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "linked.later"}, "Data")
+    ip := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "IP", Value: "10.1.0.4"}, "Data")
+    sched.Run(domain, cortex)
+    sched.Run(ip, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected no job before Domain and IP are linked, got %d", amount)
+    }
+    link := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", ID: domain.ID,
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "IP", ID: ip.ID}}},
+    }, "Data")
+    sched.Run(link, cortex)
+    if amount := countJobs(mem); amount != 2 {
+        t.Fatalf("expected the relation-only delta to add 1 job, got %d", amount)
+    }
+}
+
+// Test P.4 — Mixed direction: Domain <- IP -> Port only matches once both sides exist
+func Test_Parent_MixedDirection(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionMixed}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "mixed.example",
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "IP", Value: "10.1.0.5"}}},
+    }, "Data")
+    sched.Run(mapped, cortex)
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected no job without Port, got %d", amount)
+    }
+
+    ip := mem.Gits.Query().Execute(gits.NewQuery().Read("IP").Match("Value", "==", "10.1.0.5"))
+    port := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "IP", ID: ip.Entities[0].ID,
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "Port", Value: "443"}}},
+    }, "Data")
+    sched.Run(port, cortex)
+
+    jobs := mem.Gits.Query().Execute(gits.NewQuery().Read("Job").To(gits.NewQuery().Read("Input")))
+    if jobs.Amount != 1 {
+        t.Fatalf("expected 1 job once Port exists, got %d", jobs.Amount)
+    }
+}
+
+// Test P.5 — Absent parent: the job of an orphan IP is revoked once a Domain links it
+func Test_Parent_AbsentParent_Revoked(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionOrphan}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    ip := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "IP", Value: "10.1.0.6"}, "Data")
+    sched.Run(ip, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected 1 job for the orphan IP, got %d", amount)
+    }
+
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "adopter.example",
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "IP", ID: ip.ID}}},
+    }, "Data"), cortex)
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected the job to be revoked once the IP has a parent Domain, got %d", amount)
+    }
+}
@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+    "encoding/json"
+    "testing"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionDeepURL — Domain [Primary] -> (0..3 Subdomain) -> URL [Primary]
+type actionDeepURL struct{}
+
+func (a *actionDeepURL) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionDeepURL) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionDeepURL").SetCategory("Test")
+	dep := cfgb.NewStructure("Domain").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+		cfgb.NewStructure("URL").SetPriority(cfgb.PRIORITY_PRIMARY).SetPath(0, 3, "Subdomain"),
+	)
+	cfg.AddDependency("domainURL", dep)
+	return cfg.Build()
+}
+
+func newActionDeepURL() interfaces.ActionInterface { return &actionDeepURL{} }
+
+// chain nests the given Subdomains below each other and puts the URL at the end
+func chain(subdomains []string, url string) []transport.TransportRelation {
+    target := transport.TransportEntity{Type: "URL", Value: url}
+    for i := len(subdomains) - 1; i >= 0; i-- {
+        target = transport.TransportEntity{Type: "Subdomain", Value: subdomains[i], ChildRelations: []transport.TransportRelation{{Target: target}}}
+    }
+    return []transport.TransportRelation{{Target: target}}
+}
+
+// Test PA.1 — A URL at any depth within the bounds is handed to the job directly under the Domain
+func Test_Path_ReachedWithinBounds(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionDeepURL}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    domain := transport.TransportEntity{Type: "Domain", Value: "deep.example", ChildRelations: chain([]string{"a", "b"}, "https://b.a.deep.example/")}
+    sched.Run(mem.Mapper.MapTransportDataWithContext(domain, "Data"), cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected 1 job for the URL two Subdomains deep, got %d", amount)
+    }
+
+    inputs := mem.Gits.Query().Execute(gits.NewQuery().Read("Input"))
+    var input transport.TransportEntity
+    if err := json.Unmarshal([]byte(inputs.Entities[0].Properties["Data"]), &input); err != nil {
+        t.Fatalf("could not decode job input: %s", err)
+    }
+    if len(input.ChildRelations) != 1 || input.ChildRelations[0].Target.Type != "URL" || input.ChildRelations[0].Properties["Hops"] != "2" {
+        t.Fatalf("expected the URL directly under the Domain with 2 hops, got %+v", input.ChildRelations)
+    }
+}
+
+// Test PA.2 — URLs deeper than the max amount of hops are not reached
+func Test_Path_BeyondMax(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionDeepURL}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    domain := transport.TransportEntity{Type: "Domain", Value: "too-deep.example", ChildRelations: chain([]string{"a", "b", "c", "d"}, "https://d.c.b.a.too-deep.example/")}
+    sched.Run(mem.Mapper.MapTransportDataWithContext(domain, "Data"), cortex)
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected no job for a URL four Subdomains deep, got %d", amount)
+    }
+}
+
+// Test PA.3 — Linking an intermediate Subdomain completes the path and schedules
+func Test_Path_CompletedByRelationOnly(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionDeepURL}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    domain := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "linked.example", ChildRelations: chain([]string{"upper"}, "https://upper.linked.example/")}, "Data")
+    sched.Run(domain, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected 1 job for the URL below the upper Subdomain, got %d", amount)
+    }
+
+    lower := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Subdomain", Value: "lower", ChildRelations: chain(nil, "https://lower.upper.linked.example/")}, "Data")
+    sched.Run(lower, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected no job for a URL not reachable from a Domain, got %d", amount)
+    }
+
+    upper := domain.ChildRelations[0].Target
+    link := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Subdomain", ID: upper.ID,
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "Subdomain", ID: lower.ID}}},
+    }, "Data")
+    sched.Run(link, cortex)
+    if amount := countJobs(mem); amount != 2 {
+        t.Fatalf("expected linking the Subdomains to schedule the lower URL, got %d jobs", amount)
+    }
+}
@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+    "testing"
+    "time"
+
+    "github.com/voodooEntity/gits/src/transport"
+    "github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+    "github.com/voodooEntity/cyberbrain/src/system/util"
+)
+
+// actionPrioLow / actionPrioHigh — two actions on the same type with different job priorities
+type actionPrioLow struct{}
+
+func (a *actionPrioLow) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionPrioLow) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionPrioLow").SetCategory("Test").SetJobPriority(1)
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionPrioLow() interfaces.ActionInterface { return &actionPrioLow{} }
+
+type actionPrioHigh struct{}
+
+func (a *actionPrioHigh) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionPrioHigh) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionPrioHigh").SetCategory("Test").SetJobPriority(5)
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionPrioHigh() interfaces.ActionInterface { return &actionPrioHigh{} }
+
+func sortedOpenJobActions(mem *cerebrum.Memory, now time.Time, aging time.Duration) []string {
+    open := cerebrum.GetOpenJobs(mem)
+    if 0 == open.Amount {
+        return nil
+    }
+    jobs := open.Entities[0].Parents()
+    cerebrum.SortJobsByPriority(jobs, now, aging)
+    var names []string
+    for _, job := range jobs {
+        names = append(names, job.Properties["Action"])
+    }
+    return names
+}
+
+// Test P.1 — Jobs carry the priority of their action and higher priorities are sorted first
+func Test_Priority_ActionPriority_OrdersOpenJobs(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionPrioLow, newActionPrioHigh}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "prio-1", Properties: map[string]string{}}, "Data")
+    sched.Run(mapped, cortex)
+
+    names := sortedOpenJobActions(mem, time.Now(), 0)
+    if len(names) != 2 || names[0] != "ActionPrioHigh" || names[1] != "ActionPrioLow" {
+        t.Fatalf("expected high priority job first, got %v", names)
+    }
+}
+
+// Test P.2 — A seed priority overrules the action priority and is stored for inheritance
+func Test_Priority_SeedPriority_OverridesAction(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionPrioLow}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "prio-2", Properties: map[string]string{}}, "Data")
+    sched.RunWithPriority(mapped, cortex, "42")
+
+    open := cerebrum.GetOpenJobs(mem)
+    if open.Amount != 1 || len(open.Entities[0].Parents()) != 1 {
+        t.Fatalf("expected exactly one open job, got %+v", open)
+    }
+    job := open.Entities[0].Parents()[0]
+    if job.Properties["Priority"] != "42" || job.Properties["SeedPriority"] != "42" {
+        t.Fatalf("expected seed priority 42 on job, got %+v", job.Properties)
+    }
+}
+
+// Test P.3 — Aging lets an old low priority job overtake a fresh high priority one
+func Test_Priority_Aging_PreventsStarvation(t *testing.T) {
+    now := time.Now()
+    jobs := []transport.TransportEntity{
+        {ID: 1, Properties: map[string]string{"Priority": "5", "Created": util.Timestamp(now)}},
+        {ID: 2, Properties: map[string]string{"Priority": "1", "Created": util.Timestamp(now.Add(-time.Minute))}},
+    }
+
+    cerebrum.SortJobsByPriority(jobs, now, 0)
+    if jobs[0].ID != 1 {
+        t.Fatalf("expected high priority job first without aging, got %d", jobs[0].ID)
+    }
+
+    // one priority point every 10s -> the old job scores 1+6
+    cerebrum.SortJobsByPriority(jobs, now, 10*time.Second)
+    if jobs[0].ID != 2 {
+        t.Fatalf("expected aged job first, got %d", jobs[0].ID)
+    }
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+    "testing"
+    "time"
+
+    "github.com/voodooEntity/gits/src/transport"
+    "github.com/voodooEntity/cyberbrain/src/system/archivist"
+    "github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionRateAlias — rate limited by the Value of the aliased Alpha node
+type actionRateAlias struct{}
+
+func (a *actionRateAlias) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionRateAlias) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionRateAlias").SetCategory("Test").SetRateLimit("target", 1, time.Minute)
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetAlias("target").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionRateAlias() interfaces.ActionInterface { return &actionRateAlias{} }
+
+// actionRateType — rate limited by the Value of the Alpha type
+type actionRateType struct{}
+
+func (a *actionRateType) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionRateType) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionRateType").SetCategory("Test").SetRateLimit("Alpha", 1, time.Minute)
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionRateType() interfaces.ActionInterface { return &actionRateType{} }
+
+// Test R.1 — Jobs of different actions against the same target share one rate bucket
+func Test_RateLimit_SharedTargetBucket_DefersJobs(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionRateAlias, newActionRateType}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+    logger := archivist.New(&archivist.Config{})
+
+    mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "rate-1", Properties: map[string]string{}}, "Data")
+    sched.Run(mapped, cortex)
+
+    open := cerebrum.GetOpenJobs(mem)
+    if open.Amount != 1 || len(open.Entities[0].Parents()) != 2 {
+        t.Fatalf("expected two open jobs, got %+v", open)
+    }
+    for _, job := range open.Entities[0].Parents() {
+        if job.Properties["RateKey"] != "Alpha:rate-1" {
+            t.Fatalf("expected rate key Alpha:rate-1, got %+v", job.Properties)
+        }
+    }
+
+    activity := &cerebrum.Activity{RateLimiter: cerebrum.NewRateLimiter()}
+    first := cerebrum.NewNeuron(1, cortex, mem, activity, logger)
+    second := cerebrum.NewNeuron(2, cortex, mem, activity, logger)
+    if !first.FindJob() {
+        t.Fatalf("expected first neuron to get a job")
+    }
+    if second.FindJob() {
+        t.Fatalf("expected second job to be deferred by the shared rate limit")
+    }
+
+    // another target has its own bucket
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "rate-2", Properties: map[string]string{}}, "Data"), cortex)
+    if !second.FindJob() {
+        t.Fatalf("expected job of another target to be assigned")
+    }
+}
+
+// Test R.2 — The sliding window frees slots once older starts left it
+func Test_RateLimit_SlidingWindow(t *testing.T) {
+    limiter := cerebrum.NewRateLimiter()
+    limit := cerebrum.RateLimit{Limit: 2, Per: time.Second}
+    now := time.Now()
+
+    if !limiter.Take("IP:10.0.0.1", limit, now) || !limiter.Take("IP:10.0.0.1", limit, now.Add(500*time.Millisecond)) {
+        t.Fatalf("expected two starts within the limit")
+    }
+    if limiter.Allow("IP:10.0.0.1", limit, now.Add(900*time.Millisecond)) {
+        t.Fatalf("expected bucket to be exhausted")
+    }
+    if !limiter.Take("IP:10.0.0.1", limit, now.Add(1100*time.Millisecond)) {
+        t.Fatalf("expected a slot after the first start left the window")
+    }
+}
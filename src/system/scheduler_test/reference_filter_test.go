@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+    "testing"
+
+    "github.com/voodooEntity/gits/src/transport"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionServicePort — Host [Primary] -> Port[port] & Service[service] with Service.Properties.port == port.Value
+type actionServicePort struct{}
+
+func (a *actionServicePort) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionServicePort) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionServicePort").SetCategory("Test")
+	dep := cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+		cfgb.NewStructure("Port").SetAlias("port"),
+	).AddChild(
+		cfgb.NewStructure("Service").SetAlias("service").AddReferenceFilter("port", "Properties.port", "==", "port.Value"),
+	)
+	cfg.AddDependency("hostServicePort", dep)
+	return cfg.Build()
+}
+
+func newActionServicePort() interfaces.ActionInterface { return &actionServicePort{} }
+
+// actionDanglingReference — references an alias that doesn't exist in the tree
+type actionDanglingReference struct{}
+
+func (a *actionDanglingReference) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionDanglingReference) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionDanglingReference").SetCategory("Test")
+	dep := cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+		cfgb.NewStructure("Port").AddReferenceFilter("cert", "Value", "==", "cert.Properties.port"),
+	)
+	cfg.AddDependency("dangling", dep)
+	return cfg.Build()
+}
+
+func newActionDanglingReference() interfaces.ActionInterface { return &actionDanglingReference{} }
+
+// Test RF.1 — Only combinations where the Service port equals the Port value are scheduled
+func Test_Reference_JoinsAliases(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionServicePort}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    host := transport.TransportEntity{Type: "Host", Value: "joined", ChildRelations: []transport.TransportRelation{
+        {Target: transport.TransportEntity{Type: "Port", Value: "22"}},
+        {Target: transport.TransportEntity{Type: "Port", Value: "80"}},
+        {Target: transport.TransportEntity{Type: "Service", Value: "ssh", Properties: map[string]string{"port": "22"}}},
+        {Target: transport.TransportEntity{Type: "Service", Value: "http", Properties: map[string]string{"port": "80"}}},
+    }}
+    sched.Run(mem.Mapper.MapTransportDataWithContext(host, "Data"), cortex)
+
+    if amount := countJobs(mem); amount != 2 {
+        t.Fatalf("expected 2 jobs for the matching Port/Service pairs, got %d", amount)
+    }
+}
+
+// Test RF.2 — References to unknown aliases reject the pattern
+func Test_Reference_UnknownAliasRejected(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionDanglingReference}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    if pattern := sched.DebugGetCompiledPattern(cortex, "ActionDanglingReference", "dangling"); pattern != nil {
+        t.Fatalf("expected the pattern to be rejected, got %+v", pattern)
+    }
+    host := transport.TransportEntity{Type: "Host", Value: "dangling", ChildRelations: []transport.TransportRelation{
+        {Target: transport.TransportEntity{Type: "Port", Value: "443"}},
+    }}
+    sched.Run(mem.Mapper.MapTransportDataWithContext(host, "Data"), cortex)
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected no job for a rejected pattern, got %d", amount)
+    }
+}
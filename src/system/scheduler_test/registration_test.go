@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+    "testing"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionConfigured — action with a config handed in by the test
+type actionConfigured struct {
+	config transport.TransportEntity
+}
+
+func (a *actionConfigured) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionConfigured) GetConfig() transport.TransportEntity {
+	return a.config
+}
+
+func newActionConfigured(name string, dep *cfgb.Structure) func() interfaces.ActionInterface {
+	return func() interfaces.ActionInterface {
+		cfg := cfgb.NewConfig().SetName(name).SetCategory("Test")
+		cfg.AddDependency("dep", dep)
+		return &actionConfigured{config: cfg.Build()}
+	}
+}
+
+// Test REG.1 — Configs the scheduler can't handle are rejected on registration
+func Test_Registration_RejectsInvalidConfigs(t *testing.T) {
+    invalid := map[string]*cfgb.Structure{
+        "NoPrimary": cfgb.NewStructure("Host").AddChild(cfgb.NewStructure("Port")),
+        "AbsentPrimary": cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).SetMode(cfgb.MODE_ABSENT),
+        "DuplicateAlias": cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+            cfgb.NewStructure("Port").SetAlias("port"),
+        ).AddChild(
+            cfgb.NewStructure("Service").SetAlias("port"),
+        ),
+        "IncompleteFilter": cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddFilter("f", "", "==", "x"),
+        "UnknownOperator": cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddFilter("f", "Value", "~=", "x"),
+        "UnknownRelationOperator": cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+            cfgb.NewStructure("Port").AddRelationFilter("f", "Properties.state", "like", "open"),
+        ),
+        "MatchWithoutFilter": cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).SetMode(cfgb.MODE_MATCH),
+        "ParentLoop": cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+            cfgb.NewStructure("Port").AddParent(cfgb.NewStructure("Host")),
+        ),
+        "RootCollection": cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).SetCollection(1, 0),
+        "UnknownReference": cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+            cfgb.NewStructure("Port").AddReferenceFilter("r", "Value", "==", "cert.Value"),
+        ),
+    }
+    for name, dep := range invalid {
+        _, _, cortex := setupFreshAndSeed(nil, nil)
+        if err := cortex.RegisterAction(name, newActionConfigured(name, dep)); nil == err {
+            t.Fatalf("expected %s to be rejected", name)
+        }
+        if _, err := cortex.GetAction(name); nil == err {
+            t.Fatalf("expected rejected %s not to be registered", name)
+        }
+    }
+}
+
+// Test REG.2 — A second action with the same name is rejected, the first one stays registered
+func Test_Registration_RejectsNameCollision(t *testing.T) {
+    _, _, cortex := setupFreshAndSeed(nil, nil)
+    first := newActionConfigured("Collide", cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY))
+    second := newActionConfigured("Collide", cfgb.NewStructure("Domain").SetPriority(cfgb.PRIORITY_PRIMARY))
+
+    if err := cortex.RegisterAction("Collide", first); nil != err {
+        t.Fatalf("expected the first registration to succeed, got %v", err)
+    }
+    if err := cortex.RegisterAction("Collide", second); nil == err {
+        t.Fatalf("expected the second registration to be rejected")
+    }
+    act, err := cortex.GetAction("Collide")
+    if nil != err {
+        t.Fatalf("expected the first action to stay registered, got %v", err)
+    }
+    if root := act.GetDependencies()[0].Children()[0]; root.Value != "Host" {
+        t.Fatalf("expected the first action's dependency to be kept, got %s", root.Value)
+    }
+}
+
+// Test REG.3 — A config without category or dependency is rejected before anything is stored
+func Test_Registration_RejectsMissingDependency_NothingStored(t *testing.T) {
+    _, mem, cortex := setupFreshAndSeed(nil, nil)
+    noDependency := cfgb.NewConfig().SetName("NoDependency").SetCategory("Orphan").Build()
+    noCategory := newActionConfigured("NoCategory", cfgb.NewStructure("Host").SetPriority(cfgb.PRIORITY_PRIMARY))().GetConfig()
+    noCategory.ChildRelations = noCategory.ChildRelations[1:]
+
+    for name, config := range map[string]transport.TransportEntity{"NoDependency": noDependency, "NoCategory": noCategory} {
+        config := config
+        if err := cortex.RegisterAction(name, func() interfaces.ActionInterface { return &actionConfigured{config: config} }); nil == err {
+            t.Fatalf("expected %s to be rejected", name)
+        }
+        if stored := mem.Gits.Query().Execute(gits.NewQuery().Read("Action").Match("Value", "==", name)); stored.Amount != 0 {
+            t.Fatalf("expected the rejected %s config not to be stored, got %d", name, stored.Amount)
+        }
+    }
+    if stored := mem.Gits.Query().Execute(gits.NewQuery().Read("Category").Match("Value", "==", "Orphan")); stored.Amount != 0 {
+        t.Fatalf("expected the category of a rejected config not to be stored, got %d", stored.Amount)
+    }
+}
@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+    "strconv"
+    "testing"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionResolves — Domain [Primary] -resolvesTo-> IP [Primary]
+type actionResolves struct{}
+
+func (a *actionResolves) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionResolves) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionResolves").SetCategory("Test")
+	dep := cfgb.NewStructure("Domain").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+		cfgb.NewStructure("IP").SetPriority(cfgb.PRIORITY_PRIMARY).SetRelationContext("resolvesTo"),
+	)
+	cfg.AddDependency("domainResolvesTo", dep)
+	return cfg.Build()
+}
+
+func newActionResolves() interfaces.ActionInterface { return &actionResolves{} }
+
+// actionLongLived — Domain [Primary] -> IP [Primary] with a relation TTL above 60
+type actionLongLived struct{}
+
+func (a *actionLongLived) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionLongLived) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionLongLived").SetCategory("Test")
+	dep := cfgb.NewStructure("Domain").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+		cfgb.NewStructure("IP").SetPriority(cfgb.PRIORITY_PRIMARY).AddRelationFilter("ttl", "Properties.TTL", ">", "60"),
+	)
+	cfg.AddDependency("domainLongLived", dep)
+	return cfg.Build()
+}
+
+func newActionLongLived() interfaces.ActionInterface { return &actionLongLived{} }
+
+func domainWithTypedIPs(value string, relations map[string]string) transport.TransportEntity {
+    domain := transport.TransportEntity{Type: "Domain", Value: value}
+    for ip, context := range relations {
+        domain.ChildRelations = append(domain.ChildRelations, transport.TransportRelation{Context: context, Target: transport.TransportEntity{Type: "IP", Value: ip}})
+    }
+    return domain
+}
+
+// Test RC.1 — Only relations of the required context match, the context is persisted
+func Test_RelationContext_OnlyMatchingContext(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionResolves}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    sched.Run(mem.Mapper.MapTransportDataWithContext(domainWithTypedIPs("hosted.example", map[string]string{"10.2.0.1": "hostedOn"}), "Data"), cortex)
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected no job for a hostedOn relation, got %d", amount)
+    }
+    sched.Run(mem.Mapper.MapTransportDataWithContext(domainWithTypedIPs("mixed.example", map[string]string{"10.2.0.2": "resolvesTo", "10.2.0.3": "hostedOn"}), "Data"), cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected 1 job for the resolvesTo relation only, got %d", amount)
+    }
+
+    stored := mem.Gits.Query().Execute(gits.NewQuery().Read("Domain").Match("Value", "==", "mixed.example").To(gits.NewQuery().Read("IP").Match("Value", "==", "10.2.0.2")))
+    if stored.Amount != 1 || stored.Entities[0].ChildRelations[0].Context != "resolvesTo" {
+        t.Fatalf("expected the relation context to be persisted, got %+v", stored.Entities)
+    }
+}
+
+// Test RC.2 — Typed relation-only deltas are found by the typed relation lookup
+func Test_RelationContext_RelationOnly(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionResolves}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    lookup := mem.Gits.Query().Execute(gits.NewQuery().Read("DependencyRelationLookup").Match("Value", "==", "Domain-resolvesTo-IP"))
+    if lookup.Amount != 1 {
+        t.Fatalf("expected a typed relation lookup node, got %d", lookup.Amount)
+    }
+
+    domain := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "later.example"}, "Data")
+    ip := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "IP", Value: "10.2.0.4"}, "Data")
+    sched.Run(domain, cortex)
+    sched.Run(ip, cortex)
+    link := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", ID: domain.ID,
+        ChildRelations: []transport.TransportRelation{{Context: "resolvesTo", Target: transport.TransportEntity{Type: "IP", ID: ip.ID}}},
+    }, "Data")
+    sched.Run(link, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected the typed relation to schedule 1 job, got %d", amount)
+    }
+}
+
+// Test RC.3 — Relation property filters
+func Test_RelationContext_PropertyFilter(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionLongLived}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    domain := transport.TransportEntity{Type: "Domain", Value: "ttl.example", ChildRelations: []transport.TransportRelation{
+        {Properties: map[string]string{"TTL": "30"}, Target: transport.TransportEntity{Type: "IP", Value: "10.2.0.5"}},
+        {Properties: map[string]string{"TTL": "3600"}, Target: transport.TransportEntity{Type: "IP", Value: "10.2.0.6"}},
+    }}
+    sched.Run(mem.Mapper.MapTransportDataWithContext(domain, "Data"), cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected 1 job for the relation with TTL above 60, got %d", amount)
+    }
+}
+
+// Test RC.4 — A second context on the same entity pair is added, the first one is kept
+func Test_RelationContext_SecondContextKeepsFirst(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionResolves}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    domain := mem.Mapper.MapTransportDataWithContext(domainWithTypedIPs("both.example", map[string]string{"10.2.0.7": "hostedOn"}), "Data")
+    sched.Run(domain, cortex)
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected no job for the hostedOn relation, got %d", amount)
+    }
+    ip := domain.ChildRelations[0].Target
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", ID: domain.ID,
+        ChildRelations: []transport.TransportRelation{{Context: "resolvesTo", Target: transport.TransportEntity{Type: "IP", ID: ip.ID}}},
+    }, "Data"), cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected the added resolvesTo context to schedule 1 job, got %d", amount)
+    }
+
+    stored := mem.Gits.Query().Execute(gits.NewQuery().Read("Domain").Match("ID", "==", strconv.Itoa(domain.ID)).To(gits.NewQuery().Read("IP")))
+    relation := stored.Entities[0].ChildRelations[0]
+    if relation.Context != "hostedOn" || relation.Properties["Contexts"] != "hostedOn,resolvesTo" {
+        t.Fatalf("expected both contexts to be kept, got %q %+v", relation.Context, relation.Properties)
+    }
+}
+
+// Test RC.5 — Typing an existing untyped relation schedules typed patterns
+func Test_RelationContext_TypingExistingRelation(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionResolves}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    domain := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "untyped.example",
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "IP", Value: "10.2.0.8"}}},
+    }, "Data")
+    sched.Run(domain, cortex)
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected no job for the untyped relation, got %d", amount)
+    }
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", ID: domain.ID,
+        ChildRelations: []transport.TransportRelation{{Context: "resolvesTo", Target: transport.TransportEntity{Type: "IP", ID: domain.ChildRelations[0].Target.ID}}},
+    }, "Data"), cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected typing the relation to schedule 1 job, got %d", amount)
+    }
+}
@@ -0,0 +1,163 @@
+package scheduler
+
+import (
+    "errors"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    "github.com/voodooEntity/cyberbrain/src/system/archivist"
+    "github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+    "github.com/voodooEntity/cyberbrain/src/system/util"
+)
+
+// retryableError — error classifying itself as retryable or not
+type retryableError struct {
+	retryable bool
+}
+
+func (e retryableError) Error() string {
+	return "classified error"
+}
+
+func (e retryableError) Retryable() bool {
+	return e.retryable
+}
+
+// actionFailing — action always failing with the error handed in by the test
+type actionFailing struct {
+	err     error
+	backoff time.Duration
+}
+
+func (a *actionFailing) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+	return nil, a.err
+}
+
+func (a *actionFailing) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionFailing").SetCategory("Test").SetRetry(3, a.backoff, 0).AddRetryableError("temporary")
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionFailing(err error, backoff time.Duration) func() interfaces.ActionInterface {
+	return func() interfaces.ActionInterface { return &actionFailing{err: err, backoff: backoff} }
+}
+
+// runFailingJob schedules a job for the failing action and lets a neuron
+// execute it once. Returns the id of the job
+func runFailingJob(t *testing.T, err error, backoff time.Duration) (*cerebrum.Memory, *cerebrum.Cortex, int) {
+    actions := []func() interfaces.ActionInterface{newActionFailing(err, backoff)}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "failing", Properties: map[string]string{}}, "Data"), cortex)
+
+    id := cerebrum.GetJobIDsByState(mem, "Open")[0]
+    executeOnce(t, mem, cortex)
+    return mem, cortex, id
+}
+
+// executeOnce lets a neuron run the next open job
+func executeOnce(t *testing.T, mem *cerebrum.Memory, cortex *cerebrum.Cortex) {
+    neuron := cerebrum.NewNeuron(1, cortex, mem, nil, archivist.New(&archivist.Config{}))
+    if !neuron.FindJob() {
+        t.Fatalf("expected the neuron to get a job")
+    }
+    _, err := neuron.ExecuteJob()
+    neuron.FinishJobError(err)
+}
+
+func jobProperties(mem *cerebrum.Memory, id int) map[string]string {
+    return mem.Gits.Query().Execute(gits.NewQuery().Read("Job").Match("ID", "==", strconv.Itoa(id))).Entities[0].Properties
+}
+
+// Test R.1 — A retryable failure reopens the job with its attempt, backoff and error recorded
+func Test_Retry_Failure_ReopensWithBackoff(t *testing.T) {
+    before := time.Now()
+    mem, cortex, id := runFailingJob(t, errors.New("temporary outage"), time.Minute)
+
+    if open := cerebrum.GetJobIDsByState(mem, "Open"); len(open) != 1 || open[0] != id {
+        t.Fatalf("expected job %d to be reopened, got %v", id, open)
+    }
+    properties := jobProperties(mem, id)
+    if properties["Attempt"] != "1" {
+        t.Fatalf("expected attempt 1, got %q", properties["Attempt"])
+    }
+    if !strings.Contains(properties["LastError"], "temporary outage") {
+        t.Fatalf("expected the error to be recorded, got %q", properties["LastError"])
+    }
+    notBefore, ok := util.ParseTimestamp(properties["NotBefore"])
+    if !ok || notBefore.Before(before.Add(time.Minute-time.Second)) || notBefore.After(time.Now().Add(time.Minute)) {
+        t.Fatalf("expected NotBefore to be one backoff ahead, got %q", properties["NotBefore"])
+    }
+    if cerebrum.NewNeuron(2, cortex, mem, nil, archivist.New(&archivist.Config{})).FindJob() {
+        t.Fatalf("expected the job not to be picked up before its backoff passed")
+    }
+}
+
+// Test R.2 — The backoff doubles with every attempt and is capped by the max backoff
+func Test_Retry_Backoff_DoublesUpToCap(t *testing.T) {
+    policy := cerebrum.RetryPolicy{MaxAttempts: 10, Backoff: 100 * time.Millisecond, MaxBackoff: 350 * time.Millisecond}
+    expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 350 * time.Millisecond, 350 * time.Millisecond}
+    for i, delay := range expected {
+        if got := policy.Delay(i + 1); got != delay {
+            t.Fatalf("expected delay %s after attempt %d, got %s", delay, i+1, got)
+        }
+    }
+    policy.MaxBackoff = 0
+    if got := policy.Delay(5); got != 1600*time.Millisecond {
+        t.Fatalf("expected an uncapped delay of 1.6s after attempt 5, got %s", got)
+    }
+}
+
+// Test R.3 — With retryable errors configured only matching errors are retried
+func Test_Retry_RetryableErrors_Match(t *testing.T) {
+    mem, _, id := runFailingJob(t, errors.New("permanent failure"), time.Minute)
+    if failed := cerebrum.GetJobIDsByState(mem, "Failed"); len(failed) != 1 || failed[0] != id {
+        t.Fatalf("expected the not matching error to dead letter job %d, got %v", id, failed)
+    }
+
+    policy := cerebrum.RetryPolicy{MaxAttempts: 3, RetryableErrors: []string{"temporary"}}
+    if !policy.ShouldRetry(1, errors.New("a temporary outage")) {
+        t.Fatalf("expected a matching error to be retried")
+    }
+    if policy.ShouldRetry(3, errors.New("a temporary outage")) {
+        t.Fatalf("expected no retry once the max attempts are reached")
+    }
+}
+
+// Test R.4 — Errors implementing RetryableErrorInterface decide on their own
+func Test_Retry_RetryableErrorInterface_Decides(t *testing.T) {
+    mem, _, id := runFailingJob(t, retryableError{retryable: true}, time.Minute)
+    if open := cerebrum.GetJobIDsByState(mem, "Open"); len(open) != 1 || open[0] != id {
+        t.Fatalf("expected the retryable error to reopen job %d despite not matching, got %v", id, open)
+    }
+
+    policy := cerebrum.RetryPolicy{MaxAttempts: 3}
+    if policy.ShouldRetry(1, retryableError{retryable: false}) {
+        t.Fatalf("expected an error declaring itself not retryable to be dead lettered")
+    }
+}
+
+// Test R.5 — A job failing on its last attempt is moved to the dead letter queue
+func Test_Retry_MaxAttempts_DeadLetters(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionFailing(errors.New("temporary outage"), 0)}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "exhausted", Properties: map[string]string{}}, "Data"), cortex)
+
+    for attempt := 1; attempt <= 3; attempt++ {
+        executeOnce(t, mem, cortex)
+    }
+    failed := cerebrum.GetJobIDsByState(mem, "Failed")
+    if len(failed) != 1 {
+        t.Fatalf("expected the job to be dead lettered after 3 attempts, got %v", failed)
+    }
+    job := cerebrum.Load(failed[0], mem, archivist.New(&archivist.Config{})).GetFailedJob()
+    if job.Attempt != 3 || job.Input.Value != "exhausted" {
+        t.Fatalf("expected the failed job to keep its input after 3 attempts, got %+v", job)
+    }
+}
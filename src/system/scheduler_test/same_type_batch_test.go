@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+    "testing"
+
+    "github.com/voodooEntity/gits/src/transport"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionFanout — Domain [Primary] -> Subdomain [Secondary]
+type actionFanout struct{}
+
+func (a *actionFanout) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+    return nil, nil
+}
+
+func (a *actionFanout) GetConfig() transport.TransportEntity {
+    cfg := cfgb.NewConfig().SetName("ActionFanout").SetCategory("Test")
+    dep := cfgb.NewStructure("Domain").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+        cfgb.NewStructure("Subdomain").SetPriority(cfgb.PRIORITY_SECONDARY),
+    )
+    cfg.AddDependency("domainSubdomain", dep)
+    return cfg.Build()
+}
+
+func newActionFanout() interfaces.ActionInterface { return &actionFanout{} }
+
+// actionNested — Directory [Primary] -> Directory [Secondary], same type on two levels
+type actionNested struct{}
+
+func (a *actionNested) Execute(input transport.TransportEntity, requirement, context, jobID string) ([]transport.TransportEntity, error) {
+    return nil, nil
+}
+
+func (a *actionNested) GetConfig() transport.TransportEntity {
+    cfg := cfgb.NewConfig().SetName("ActionNested").SetCategory("Test")
+    dep := cfgb.NewStructure("Directory").SetPriority(cfgb.PRIORITY_PRIMARY).AddChild(
+        cfgb.NewStructure("Directory").SetPriority(cfgb.PRIORITY_SECONDARY),
+    )
+    cfg.AddDependency("dirSubdir", dep)
+    return cfg.Build()
+}
+
+func newActionNested() interfaces.ActionInterface { return &actionNested{} }
+
+// Test ST.1 — One Domain with ten new Subdomains yields one job per Subdomain
+func Test_SameType_ManyNewChildren(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionFanout}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    var children []transport.TransportRelation
+    for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+        children = append(children, transport.TransportRelation{Target: transport.TransportEntity{Type: "Subdomain", Value: name + ".fanout.example"}})
+    }
+    mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "fanout.example", ChildRelations: children}, "Data")
+    sched.Run(mapped, cortex)
+
+    if amount := countJobs(mem); amount != 10 {
+        t.Fatalf("expected 10 jobs for 10 new Subdomains, got %d", amount)
+    }
+    // Re-running the same batch must not create further jobs
+    sched.Run(mapped, cortex)
+    if amount := countJobs(mem); amount != 10 {
+        t.Fatalf("expected re-run to be deduplicated by witness, got %d jobs", amount)
+    }
+}
+
+// Test ST.2 — New entities of the same type on two levels of one batch match each other
+func Test_SameType_NestedLevels(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionNested}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Directory", Value: "/srv",
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "Directory", Value: "/srv/www",
+            ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "Directory", Value: "/srv/www/static"}}},
+        }}},
+    }, "Data")
+    sched.Run(mapped, cortex)
+
+    // /srv -> /srv/www and /srv/www -> /srv/www/static
+    if amount := countJobs(mem); amount != 2 {
+        t.Fatalf("expected 2 jobs for the nested Directories, got %d", amount)
+    }
+}
@@ -70,7 +70,7 @@ func createBaseData(mem *cerebrum.Memory) {
 		ID:         0,
 		Type:       "State",
 		Value:      "Open",
-		Context:    "System",
+		Context:    mem.Scope("System"),
 		Properties: make(map[string]string),
 	})
 	// create Assigned state
@@ -78,16 +78,33 @@ func createBaseData(mem *cerebrum.Memory) {
 		ID:         0,
 		Type:       "State",
 		Value:      "Assigned",
-		Context:    "System",
+		Context:    mem.Scope("System"),
+		Properties: make(map[string]string),
+	})
+	// create Done state for finished jobs kept as history
+	mem.Gits.MapData(transport.TransportEntity{
+		ID:         0,
+		Type:       "State",
+		Value:      "Done",
+		Context:    mem.Scope("System"),
+		Properties: make(map[string]string),
+	})
+	// create Failed state, our dead letter queue
+	mem.Gits.MapData(transport.TransportEntity{
+		ID:         0,
+		Type:       "State",
+		Value:      "Failed",
+		Context:    mem.Scope("System"),
 		Properties: make(map[string]string),
 	})
 	// create alife dataset
 	properties := make(map[string]string)
 	properties["State"] = "Alive"
-	mem.Mapper.MapTransportData(transport.TransportEntity{
+	mem.Gits.MapData(transport.TransportEntity{
+		ID:         0,
 		Type:       "AI",
 		Value:      "Cyberbrain",
-		Context:    "System",
+		Context:    mem.Scope("System"),
 		Properties: properties,
 	})
 }
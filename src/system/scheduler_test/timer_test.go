@@ -0,0 +1,183 @@
+package scheduler
+
+import (
+    "strconv"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    "github.com/voodooEntity/cyberbrain/src/system/archivist"
+    "github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionPeriodic — re-run against every Alpha once per hour
+type actionPeriodic struct{}
+
+func (a *actionPeriodic) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionPeriodic) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionPeriodic").SetCategory("Test").SetInterval(time.Hour)
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionPeriodic() interfaces.ActionInterface { return &actionPeriodic{} }
+
+// actionDelayed — runs once ten minutes after an Alpha appeared
+type actionDelayed struct{}
+
+func (a *actionDelayed) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionDelayed) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionDelayed").SetCategory("Test").SetDelay(10 * time.Minute)
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionDelayed() interfaces.ActionInterface { return &actionDelayed{} }
+
+func countJobs(mem *cerebrum.Memory) int {
+    return mem.Gits.Query().Execute(gits.NewQuery().Read("Job")).Amount
+}
+
+// Test T.1 — Periodic runs create one job per input and time bucket
+func Test_Timer_PeriodicRun_OneJobPerBucket(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionPeriodic}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    for _, value := range []string{"tick-1", "tick-2"} {
+        sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: value, Properties: map[string]string{}}, "Data"), cortex)
+    }
+    if amount := countJobs(mem); amount != 2 {
+        t.Fatalf("expected 2 data driven jobs, got %d", amount)
+    }
+
+    act, _ := cortex.GetAction("ActionPeriodic")
+    if created := sched.RunPeriodic(act, "1", 1); created != 2 {
+        t.Fatalf("expected 2 periodic jobs for bucket 1, got %d", created)
+    }
+    if created := sched.RunPeriodic(act, "1", 1); created != 0 {
+        t.Fatalf("expected bucket 1 to be deduplicated, got %d", created)
+    }
+    if created := sched.RunPeriodic(act, "2", 1); created != 2 {
+        t.Fatalf("expected 2 periodic jobs for bucket 2, got %d", created)
+    }
+    if amount := countJobs(mem); amount != 6 {
+        t.Fatalf("expected 6 jobs overall, got %d", amount)
+    }
+}
+
+// Test T.2 — The timer only runs an action once per interval bucket
+func Test_Timer_Tick_RunsOncePerInterval(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionPeriodic}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "tick-3", Properties: map[string]string{}}, "Data"), cortex)
+
+    timer := cerebrum.NewTimer(mem, cortex, &sched, archivist.New(&archivist.Config{}))
+    now := time.Now().Truncate(time.Hour)
+    if created := timer.Tick(now); created != 1 {
+        t.Fatalf("expected first tick to create 1 job, got %d", created)
+    }
+    if created := timer.Tick(now.Add(30 * time.Minute)); created != 0 {
+        t.Fatalf("expected no jobs within the same interval, got %d", created)
+    }
+    if created := timer.Tick(now.Add(time.Hour)); created != 1 {
+        t.Fatalf("expected next interval to create 1 job, got %d", created)
+    }
+}
+
+// Test T.3 — Delayed actions create jobs that are not due before the delay passed
+func Test_Timer_Delay_SetsNotBefore(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionDelayed}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "later-1", Properties: map[string]string{}}, "Data"), cortex)
+
+    open := cerebrum.GetOpenJobs(mem)
+    if open.Amount != 1 || len(open.Entities[0].Parents()) != 1 {
+        t.Fatalf("expected one open job, got %+v", open)
+    }
+    job := open.Entities[0].Parents()[0]
+    if cerebrum.IsDue(job.Properties, time.Now()) {
+        t.Fatalf("expected delayed job not to be due yet")
+    }
+    if !cerebrum.IsDue(job.Properties, time.Now().Add(11*time.Minute)) {
+        t.Fatalf("expected delayed job to be due after the delay")
+    }
+}
+
+// Test T.4 — Timer ticks and scheduling share the pattern cache concurrently (run with -race)
+func Test_Timer_Tick_ConcurrentToScheduling(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionPeriodic}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+    timer := cerebrum.NewTimer(mem, cortex, &sched, archivist.New(&archivist.Config{}))
+    now := time.Now().Truncate(time.Hour)
+
+    var wg sync.WaitGroup
+    // like the timer loop, a single goroutine ticks
+    wg.Add(1)
+    go func() {
+        defer wg.Done()
+        for i := 0; i < 32; i++ {
+            timer.Tick(now.Add(time.Duration(i) * time.Hour))
+        }
+    }()
+    for i := 0; i < 32; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "race-" + strconv.Itoa(i), Properties: map[string]string{}}, "Data"), cortex)
+        }(i)
+    }
+    wg.Wait()
+    if amount := countJobs(mem); amount == 0 {
+        t.Fatalf("expected jobs to be created")
+    }
+}
+
+// Test T.5 — Witnesses of past buckets expire, so periodic runs don't pile them up
+func Test_Timer_Tick_BucketWitnessesExpire(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionPeriodic}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+    sched.Run(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "bounded", Properties: map[string]string{}}, "Data"), cortex)
+
+    timer := cerebrum.NewTimer(mem, cortex, &sched, archivist.New(&archivist.Config{}))
+    neuron := cerebrum.NewNeuron(1, cortex, mem, nil, archivist.New(&archivist.Config{}))
+    now := time.Now().Truncate(time.Hour)
+    for i := 0; i < 6; i++ {
+        if created := timer.Tick(now.Add(time.Duration(i) * time.Hour)); created != 1 {
+            t.Fatalf("expected tick %d to create 1 job, got %d", i, created)
+        }
+        for neuron.FindJob() {
+            results, _ := neuron.ExecuteJob()
+            neuron.FinishJobSuccess(results)
+        }
+        // the witness of the data driven job has no ttl and stays
+        sched.SweepWitnesses(time.Now().Add(time.Hour + time.Minute))
+        if witnesses := mem.Gits.Query().Execute(gits.NewQuery().Read("Memory")); witnesses.Amount != 1 {
+            t.Fatalf("expected the bucket witnesses to be swept after tick %d, got %d witnesses", i, witnesses.Amount)
+        }
+    }
+}
+
+// Test T.6 — Periodic runs page over the roots and skip the cyberbrain's own contexts
+func Test_Timer_PeriodicRun_SkipsInternal(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionPeriodic}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+    for i := 0; i < 3; i++ {
+        mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "paged-" + strconv.Itoa(i), Properties: map[string]string{}}, "Data")
+    }
+    mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "internal", Properties: map[string]string{}}, mem.Scope("Cyberbrain"))
+
+    act, _ := cortex.GetAction("ActionPeriodic")
+    if created := sched.RunPeriodic(act, "1", 2); created != 3 {
+        t.Fatalf("expected 3 periodic jobs, got %d", created)
+    }
+}
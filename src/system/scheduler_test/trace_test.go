@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+    "testing"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    "github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// Test TR.1 — The trace lists the candidates, the created jobs and the witness hit on a rerun
+func Test_Trace_Run_RecordsJobsAndWitness(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionResolve}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    domain := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "traced.example"}, "Data")
+    trace := sched.RunWithTrace(domain, cortex)
+    if len(trace.Candidates) != 1 || trace.Candidates[0] != "ActionResolve:domain" {
+        t.Fatalf("expected ActionResolve:domain as only candidate, got %v", trace.Candidates)
+    }
+    if amount := countJobs(mem); amount != 1 || len(trace.Jobs) != 1 {
+        t.Fatalf("expected 1 job created and traced, got %d created and %v traced\n%s", amount, trace.Jobs, trace)
+    }
+
+    trace = sched.RunWithTrace(domain, cortex)
+    if _, rejected := trace.Rejected("ActionResolve", "domain", cerebrum.TRACE_STEP_WITNESS); !rejected || len(trace.Jobs) != 0 {
+        t.Fatalf("expected the rerun to be rejected by the witness\n%s", trace)
+    }
+}
+
+// Test TR.2 — Inputs dropped while parsing are traced with their step
+func Test_Trace_Run_RecordsAbsence(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionResolve}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    trace := sched.RunWithTrace(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "resolved.example",
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "IP", Value: "10.0.0.1"}}},
+    }, "Data"), cortex)
+    decision, rejected := trace.Rejected("ActionResolve", "domain", cerebrum.TRACE_STEP_ABSENCE)
+    if !rejected || decision.Input == "" {
+        t.Fatalf("expected the input to be rejected by the absence condition\n%s", trace)
+    }
+}
+
+// Test TR.3 — Explaining is a dry run, it neither creates jobs nor witnesses
+func Test_Trace_Explain_DryRun(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionResolve}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    domain := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "explained.example"}, "Data")
+    trace, err := sched.Explain(cortex, "ActionResolve", "domain", "Domain", domain.ID)
+    if nil != err {
+        t.Fatalf("expected explain to succeed, got %v", err)
+    }
+    decisions := trace.DecisionsFor("ActionResolve", "domain")
+    if last := decisions[len(decisions)-1]; last.Step != cerebrum.TRACE_STEP_JOB || !last.Passed {
+        t.Fatalf("expected a job to be explained\n%s", trace)
+    }
+    if amount := countJobs(mem); amount != 0 {
+        t.Fatalf("expected explain to create no job, got %d", amount)
+    }
+    if witnesses := mem.Gits.Query().Execute(gits.NewQuery().Read("Memory")); witnesses.Amount != 0 {
+        t.Fatalf("expected explain to create no witness, got %d", witnesses.Amount)
+    }
+
+    sched.Run(domain, cortex)
+    trace, _ = sched.Explain(cortex, "ActionResolve", "domain", "Domain", domain.ID)
+    if _, rejected := trace.Rejected("ActionResolve", "domain", cerebrum.TRACE_STEP_WITNESS); !rejected {
+        t.Fatalf("expected the scheduled input to be explained as witnessed\n%s", trace)
+    }
+    if _, err := sched.Explain(cortex, "ActionResolve", "missing", "Domain", domain.ID); nil == err {
+        t.Fatalf("expected an unknown dependency to return an error")
+    }
+}
+
+// Test TR.4 — Tracing a batch leaves open jobs alone, even if an absent node appeared
+func Test_Trace_Run_DoesntRevoke(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionResolve}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    domain := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", Value: "kept.example"}, "Data")
+    sched.Run(domain, cortex)
+    sched.RunWithTrace(mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Domain", ID: domain.ID,
+        ChildRelations: []transport.TransportRelation{{Target: transport.TransportEntity{Type: "IP", Value: "10.0.0.3"}}},
+    }, "Data"), cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected tracing to keep the open job, got %d jobs", amount)
+    }
+}
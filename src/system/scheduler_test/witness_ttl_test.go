@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+    "testing"
+    "time"
+
+    "github.com/voodooEntity/gits"
+    "github.com/voodooEntity/gits/src/transport"
+    "github.com/voodooEntity/cyberbrain/src/system/archivist"
+    "github.com/voodooEntity/cyberbrain/src/system/cerebrum"
+    cfgb "github.com/voodooEntity/cyberbrain/src/system/configBuilder"
+    "github.com/voodooEntity/cyberbrain/src/system/interfaces"
+)
+
+// actionStale — its witnesses expire shortly after scheduling
+type actionStale struct{}
+
+func (a *actionStale) Execute(input transport.TransportEntity, requirement string, context string, jobID string) ([]transport.TransportEntity, error) {
+	return nil, nil
+}
+
+func (a *actionStale) GetConfig() transport.TransportEntity {
+	cfg := cfgb.NewConfig().SetName("ActionStale").SetCategory("Test").SetWitnessTTL(50 * time.Millisecond)
+	cfg.AddDependency("alpha", cfgb.NewStructure("Alpha").SetPriority(cfgb.PRIORITY_PRIMARY))
+	return cfg.Build()
+}
+
+func newActionStale() interfaces.ActionInterface { return &actionStale{} }
+
+func finishOpenJobs(mem *cerebrum.Memory) {
+    open := cerebrum.GetOpenJobs(mem)
+    if 0 == open.Amount {
+        return
+    }
+    for _, job := range open.Entities[0].Parents() {
+        cerebrum.Load(job.ID, mem, archivist.New(&archivist.Config{})).Delete()
+    }
+}
+
+// Test W.1 — An expired witness allows rescheduling once the previous job finished
+func Test_WitnessTTL_Expired_AllowsReschedule(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionStale}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "stale-1", Properties: map[string]string{}}, "Data")
+    sched.Run(mapped, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected 1 job, got %d", amount)
+    }
+
+    witnesses := mem.Gits.Query().Execute(gits.NewQuery().Read("Memory"))
+    if witnesses.Amount != 1 || witnesses.Entities[0].Properties["Created"] == "" || witnesses.Entities[0].Properties["Expires"] == "" {
+        t.Fatalf("expected one stamped witness, got %+v", witnesses)
+    }
+
+    // within the ttl the witness holds
+    sched.Run(mapped, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected witness to prevent a second job, got %d", amount)
+    }
+
+    // expired, but the job is still open
+    time.Sleep(60 * time.Millisecond)
+    sched.Run(mapped, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected active job to prevent rescheduling, got %d", amount)
+    }
+
+    finishOpenJobs(mem)
+    sched.Run(mapped, cortex)
+    if amount := countJobs(mem); amount != 1 {
+        t.Fatalf("expected expired witness to allow rescheduling, got %d", amount)
+    }
+}
+
+// Test W.2 — The sweep deletes expired witnesses and their anchor relations
+func Test_WitnessTTL_Sweep_DeletesExpired(t *testing.T) {
+    actions := []func() interfaces.ActionInterface{newActionStale, newActionA}
+    sched, mem, cortex := setupFreshAndSeed(nil, actions)
+
+    mapped := mem.Mapper.MapTransportDataWithContext(transport.TransportEntity{Type: "Alpha", Value: "stale-2", Properties: map[string]string{}}, "Data")
+    sched.Run(mapped, cortex)
+    finishOpenJobs(mem)
+
+    if deleted := sched.SweepWitnesses(time.Now()); deleted != 0 {
+        t.Fatalf("expected nothing to sweep before expiry, got %d", deleted)
+    }
+    if deleted := sched.SweepWitnesses(time.Now().Add(time.Second)); deleted != 1 {
+        t.Fatalf("expected one expired witness to be swept, got %d", deleted)
+    }
+
+    // the witness of ActionA has no ttl and stays
+    witnesses := mem.Gits.Query().Execute(gits.NewQuery().Read("Memory"))
+    if witnesses.Amount != 1 || witnesses.Entities[0].Properties["Expires"] != "" {
+        t.Fatalf("expected only the permanent witness to remain, got %+v", witnesses)
+    }
+    linked := mem.Gits.Query().Execute(gits.NewQuery().Read("Alpha").Match("Value", "==", "stale-2").To(gits.NewQuery().Read("Memory")))
+    if linked.Amount != 1 || len(linked.Entities[0].ChildRelations) != 1 {
+        t.Fatalf("expected anchor to be linked to the permanent witness only, got %+v", linked)
+    }
+}
@@ -6,18 +6,34 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/voodooEntity/gits"
 	"github.com/voodooEntity/gits/src/query"
 	"github.com/voodooEntity/gits/src/transport"
 )
 
-func IsAlive(gitsInstance *gits.Gits) bool {
+// Scope namespaces a system context by the given cyberbrain ident so
+// multiple brains can share one graph. Without an ident the context is
+// returned unchanged
+func Scope(context string, ident string) string {
+	if "" == ident {
+		return context
+	}
+	return context + "@" + ident
+}
+
+func IsAlive(gitsInstance *gits.Gits, ident string) bool {
 	qry := query.New().Read("AI").Match(
 		"Value",
 		"==",
 		"Cyberbrain",
+	).Match(
+		"Context",
+		"==",
+		Scope("System", ident),
 	).Match(
 		"Properties.State",
 		"==",
@@ -30,11 +46,15 @@ func IsAlive(gitsInstance *gits.Gits) bool {
 	return false
 }
 
-func Terminate(gitsInstance *gits.Gits) bool {
+func Terminate(gitsInstance *gits.Gits, ident string) bool {
 	qry := query.New().Update("AI").Match(
 		"Value",
 		"==",
 		"Cyberbrain",
+	).Match(
+		"Context",
+		"==",
+		Scope("System", ident),
 	).Set(
 		"Properties.State",
 		"Dead",
@@ -78,6 +98,25 @@ func CopyStringStringMap(data map[string]string) map[string]string {
 	return ret
 }
 
+// Timestamp formats the given time as unix milliseconds, the format
+// used for all time related properties on system entities
+func Timestamp(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+// ParseTimestamp parses a timestamp created by Timestamp. The second return
+// value is false if the given value is empty or malformed
+func ParseTimestamp(value string) (time.Time, bool) {
+	if "" == value {
+		return time.Time{}, false
+	}
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if nil != err {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(millis), true
+}
+
 func ResolveEntityField(entity transport.TransportEntity, field string) string {
 	switch field {
 	case "Value":
@@ -94,6 +133,172 @@ func ResolveEntityField(entity transport.TransportEntity, field string) string {
 	return ""
 }
 
+// RelationContexts returns all contexts of a relation. The first one is the
+// relation context, further ones are kept in the Contexts property since only
+// one relation per entity pair exists
+func RelationContexts(context string, properties map[string]string) []string {
+	if contexts, ok := properties["Contexts"]; ok && "" != contexts {
+		return strings.Split(contexts, ",")
+	}
+	if "" == context {
+		return []string{}
+	}
+	return []string{context}
+}
+
+// MatchRelationField compares a field of the relation with MatchValue. A relation
+// with several contexts matches Context if any of them does, or for != if all do
+func MatchRelationField(relation transport.TransportRelation, field string, operator string, value string) bool {
+	if "Context" != field {
+		return MatchValue(ResolveRelationField(relation, field), operator, value)
+	}
+	contexts := RelationContexts(relation.Context, relation.Properties)
+	if 0 == len(contexts) {
+		contexts = []string{""}
+	}
+	// != has to hold for every context, any other operator for one of them
+	if "!=" == operator {
+		for _, context := range contexts {
+			if !MatchValue(context, operator, value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, context := range contexts {
+		if MatchValue(context, operator, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveRelationField returns the value of the given field of a relation,
+// either "Context" or "Properties.<key>"
+func ResolveRelationField(relation transport.TransportRelation, field string) string {
+	if "Context" == field {
+		return relation.Context
+	}
+	if len(field) > 11 && field[:11] == "Properties." {
+		return relation.Properties[field[11:]]
+	}
+	return ""
+}
+
+// MatchValue compares alpha against beta with the given operator, supporting
+// the same operators as gits query matches
+func MatchValue(alpha string, operator string, beta string) bool {
+	switch operator {
+	case "==":
+		return alpha == beta
+	case "!=":
+		return alpha != beta
+	case "prefix":
+		return strings.HasPrefix(alpha, beta)
+	case "suffix":
+		return strings.HasSuffix(alpha, beta)
+	case "contain":
+		return strings.Contains(alpha, beta)
+	case "in":
+		for _, value := range strings.Split(beta, ",") {
+			if alpha == value {
+				return true
+			}
+		}
+		return false
+	case ">", ">=", "<", "<=":
+		alphaInt, err := strconv.Atoi(alpha)
+		if nil != err {
+			return false
+		}
+		betaInt, err := strconv.Atoi(beta)
+		if nil != err {
+			return false
+		}
+		switch operator {
+		case ">":
+			return alphaInt > betaInt
+		case ">=":
+			return alphaInt >= betaInt
+		case "<":
+			return alphaInt < betaInt
+		}
+		return alphaInt <= betaInt
+	}
+	return false
+}
+
+// GenerateIdentitySignature creates a deterministic signature of the structure
+// of a TransportEntity only, content and Version are ignored.
+// Format: [Type:ID](SortedChildSignatures)(SortedParentSignatures)
+func GenerateIdentitySignature(entity transport.TransportEntity) string {
+	return generateSignatureWith(entity, func(e transport.TransportEntity) string {
+		return fmt.Sprintf("[%s:%d]", e.Type, e.ID)
+	})
+}
+
+// GenerateFieldSignature creates a deterministic signature of the structure of a
+// TransportEntity plus the given fields ("Value" or "Properties.<key>") of every node.
+// Format: [Type:ID{field="value";...}](SortedChildSignatures)(SortedParentSignatures)
+func GenerateFieldSignature(entity transport.TransportEntity, fields []string) string {
+	sortedFields := make([]string, len(fields))
+	copy(sortedFields, fields)
+	sort.Strings(sortedFields)
+	return generateSignatureWith(entity, func(e transport.TransportEntity) string {
+		var sb strings.Builder
+		for _, field := range sortedFields {
+			sb.WriteString(fmt.Sprintf("%s=%q;", field, ResolveEntityField(e, field)))
+		}
+		return fmt.Sprintf("[%s:%d{%s}]", e.Type, e.ID, sb.String())
+	})
+}
+
+// GenerateContentSignature creates a deterministic signature of the structure of a
+// TransportEntity plus Value and all properties of every node. Version, Context
+// and the mappers bMap flag are ignored.
+// Format: [Type:ID:"Value"{key="value";...}](SortedChildSignatures)(SortedParentSignatures)
+func GenerateContentSignature(entity transport.TransportEntity) string {
+	return generateSignatureWith(entity, func(e transport.TransportEntity) string {
+		keys := make([]string, 0, len(e.Properties))
+		for key := range e.Properties {
+			if "bMap" != key {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		var sb strings.Builder
+		for _, key := range keys {
+			sb.WriteString(fmt.Sprintf("%s=%q;", key, e.Properties[key]))
+		}
+		return fmt.Sprintf("[%s:%d:%q{%s}]", e.Type, e.ID, e.Value, sb.String())
+	})
+}
+
+// generateSignatureWith walks a TransportEntity like GenerateSignature
+// but builds the part of every node with the given function
+func generateSignatureWith(entity transport.TransportEntity, self func(transport.TransportEntity) string) string {
+	return fmt.Sprintf("%s(%s)(%s)", self(entity), processRelationsWith(entity.ChildRelations, self), processRelationsWith(entity.ParentRelations, self))
+}
+
+func processRelationsWith(relations []transport.TransportRelation, self func(transport.TransportEntity) string) string {
+	if len(relations) == 0 {
+		return ""
+	}
+	sortedRels := make([]transport.TransportRelation, len(relations))
+	copy(sortedRels, relations)
+	sort.Slice(sortedRels, func(i, j int) bool {
+		if sortedRels[i].Target.Type != sortedRels[j].Target.Type {
+			return sortedRels[i].Target.Type < sortedRels[j].Target.Type
+		}
+		return sortedRels[i].Target.ID < sortedRels[j].Target.ID
+	})
+	var sb strings.Builder
+	for _, rel := range sortedRels {
+		sb.WriteString(generateSignatureWith(rel.Target, self))
+	}
+	return sb.String()
+}
+
 // - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - vv
 // BELOW THIS LINE ARE DEBUGGING HELPERS ONLY
 // GenerateSignature creates a deterministic string signature for a TransportEntity.